@@ -8,20 +8,26 @@ import (
 	"context"
 	"fmt"
 	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/introspection"
 	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/realm"
 	"issuer-service-go/internal/server"
 	"issuer-service-go/internal/version"
+	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
-func gracefulShutdown(fiberServer *server.FiberServer, done chan bool) {
-	// Create context that listens for the interrupt signal from the OS.
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
+const (
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
 
+func gracefulShutdown(ctx context.Context, done chan bool, fiberServers ...*server.FiberServer) {
 	// Listen for the interrupt signal.
 	<-ctx.Done()
 
@@ -31,8 +37,10 @@ func gracefulShutdown(fiberServer *server.FiberServer, done chan bool) {
 	// the request it is currently handling
 	ctx, cancel := context.WithTimeout(context.Background(), config.GetConfig().GracefulShutdownTimeout)
 	defer cancel()
-	if err := fiberServer.ShutdownWithContext(ctx); err != nil {
-		log.Printf("Server forced to shutdown with error: %v", err)
+	for _, fiberServer := range fiberServers {
+		if err := fiberServer.ShutdownWithContext(ctx); err != nil {
+			log.Printf("Server forced to shutdown with error: %v", err)
+		}
 	}
 
 	log.Info().Msg("Server exiting")
@@ -41,16 +49,149 @@ func gracefulShutdown(fiberServer *server.FiberServer, done chan bool) {
 	done <- true
 }
 
+func newJwksProvider(ctx context.Context, appConfig *config.Config) (jwks.Provider, error) {
+	switch appConfig.JwksProvider {
+	case config.ProviderFile, config.ProviderDirectory:
+		jwksConfig := &appConfig.JwksConfig
+		newProvider := func(realm string) (jwks.Provider, error) {
+			if realm != "" {
+				return newFileProvider(ctx, appConfig.JwksProvider, jwksConfig.ForRealm(realm))
+			}
+			return newFileProvider(ctx, appConfig.JwksProvider, jwksConfig)
+		}
+		return newRealmProvider(jwksConfig.Realms(), jwksConfig.MountedPath != "", newProvider)
+	case config.ProviderKubernetes:
+		return newKubernetesProvider(ctx, &appConfig.KubernetesConfig)
+	default:
+		return nil, fmt.Errorf("unknown JWKS provider '%s'", appConfig.JwksProvider)
+	}
+}
+
+func newFileProvider(
+	ctx context.Context,
+	providerType string,
+	jwksConfig *config.JwksFileConfig,
+) (jwks.Provider, error) {
+	if providerType == config.ProviderDirectory {
+		return jwks.NewDirectoryProvider(ctx, jwksConfig)
+	}
+	return jwks.NewFileProvider(ctx, jwksConfig)
+}
+
+func newKubernetesProvider(ctx context.Context, k8sConfig *config.JwksKubernetesConfig) (jwks.Provider, error) {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load in-cluster config: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	if k8sConfig.Namespace == "" {
+		namespace, readErr := os.ReadFile(serviceAccountNamespaceFile)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read namespace of the pod: %w", readErr)
+		}
+		k8sConfig.Namespace = strings.TrimSpace(string(namespace))
+	}
+
+	newProvider := func(realm string) (jwks.Provider, error) {
+		if realm != "" {
+			return jwks.NewKubernetesProvider(ctx, client, k8sConfig.ForRealm(realm))
+		}
+		return jwks.NewKubernetesProvider(ctx, client, k8sConfig)
+	}
+	return newRealmProvider(k8sConfig.Realms(), k8sConfig.SecretNameActive != "", newProvider)
+}
+
+// newRealmProvider creates a provider per realm with keys of its own and the provider of the keys served for
+// all other realms, which is identified by an empty realm. The latter is optional if any realm has keys of
+// its own.
+func newRealmProvider(
+	realms []string,
+	hasDefault bool,
+	newProvider func(realm string) (jwks.Provider, error),
+) (jwks.Provider, error) {
+	if len(realms) == 0 {
+		return newProvider("")
+	}
+
+	var defaultProvider jwks.Provider
+	if hasDefault {
+		provider, err := newProvider("")
+		if err != nil {
+			return nil, err
+		}
+		defaultProvider = provider
+	}
+
+	realmProviders := make(map[string]jwks.Provider, len(realms))
+	for _, realm := range realms {
+		provider, err := newProvider(realm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create JWKS provider of realm %s: %w", realm, err)
+		}
+		realmProviders[realm] = provider
+	}
+
+	log.Info().Msgf("serving separate keys for the realms %v", realms)
+	return jwks.NewRealmProvider(defaultProvider, realmProviders), nil
+}
+
 func main() {
 	log.Info().Msgf("%s\n", version.GetVersionInfo())
 
 	appConfig := config.GetConfig()
 
-	jwksProvider, err := jwks.NewFileProvider(&appConfig.JwksConfig)
+	// Create context that listens for the interrupt signal from the OS. It also stops the reloads of the keys.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	jwksProvider, err := newJwksProvider(ctx, appConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to create JWKS %s provider", appConfig.JwksProvider)
+	}
+	realmRegistry, err := realm.NewRegistry(&appConfig.RealmConfig, jwks.ProviderRealms(jwksProvider))
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to create JWKS file provider")
+		log.Fatal().Err(err).Msg("Failed to create realm registry")
+	}
+	discoveryMetadata, err := server.NewDiscoveryMetadata(appConfig.ServerConfig.DiscoveryMetadataFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load discovery metadata")
+	}
+	introspectionClients, err := introspection.NewClients(appConfig.IntrospectionConfig.ClientsFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load introspection clients")
+	}
+	var metadataSigner *jwks.MetadataSigner
+	if appConfig.MetadataSigningConfig.MountedPath != "" {
+		metadataSigner, err = jwks.NewMetadataSigner(ctx, &appConfig.MetadataSigningConfig)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load metadata signing key")
+		}
+	}
+	issuerResolver, err := server.NewIssuerResolver(&appConfig.IssuerConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create issuer resolver")
+	}
+	handler := server.NewHandler(jwksProvider, server.HandlerOptions{
+		RealmRegistry:        realmRegistry,
+		DiscoveryMetadata:    discoveryMetadata,
+		IntrospectionClients: introspectionClients,
+		MetadataSigner:       metadataSigner,
+		IssuerResolver:       issuerResolver,
+	})
+
+	var adminSrv *server.FiberServer
+	if appConfig.AdminConfig.IsEnabled() {
+		adminHandler, adminErr := server.NewAdminHandler(jwksProvider, appConfig.AdminConfig.TokenFile)
+		if adminErr != nil {
+			log.Fatal().Err(adminErr).Msg("Failed to create admin API")
+		}
+		adminSrv = server.NewAdminServer(adminHandler)
 	}
-	handler := server.NewHandler(jwksProvider)
 
 	srv := server.New()
 	srv.RegisterRoutes(handler)
@@ -58,13 +199,25 @@ func main() {
 	done := make(chan bool, 1)
 
 	go func() {
-		srvError := srv.Listen(fmt.Sprintf(":%d", appConfig.ServerConfig.Port))
+		srvError := srv.Start(&appConfig.ServerConfig)
 		if srvError != nil {
 			panic(fmt.Sprintf("http server error: %s", srvError))
 		}
 	}()
 
-	go gracefulShutdown(srv, done)
+	fiberServers := []*server.FiberServer{srv}
+	if adminSrv != nil {
+		fiberServers = append(fiberServers, adminSrv)
+
+		go func() {
+			adminSrvError := adminSrv.StartAdmin(&appConfig.AdminConfig)
+			if adminSrvError != nil {
+				panic(fmt.Sprintf("admin server error: %s", adminSrvError))
+			}
+		}()
+	}
+
+	go gracefulShutdown(ctx, done, fiberServers...)
 
 	<-done
 	log.Info().Msg("Graceful shutdown complete.")
@@ -5,35 +5,149 @@
 package config
 
 import (
+	"maps"
 	"path"
+	"slices"
+	"strings"
 	"time"
 )
 
 type Config struct {
 	LogLevel string `env:"LOG_LEVEL,expand" envDefault:"info"` // Log level of the application
 
-	GracefulShutdownTimeout time.Duration `env:"GRACEFUL_SHUTDOWN_TIMEOUT,expand" envDefault:"5s"` // Timeout in seconds for graceful shutdown
-	PathPrefix              string        `env:"PATH_PREFIX,expand"               envDefault:""`   // Prefixed to DiscoveryInfo URLs returned by issuer-service (e.g. /spacegate)
+	GracefulShutdownTimeout time.Duration `env:"GRACEFUL_SHUTDOWN_TIMEOUT,expand" envDefault:"5s"`   // Timeout in seconds for graceful shutdown
+	PathPrefix              string        `env:"PATH_PREFIX,expand"               envDefault:""`     // Prefixed to DiscoveryInfo URLs returned by issuer-service (e.g. /spacegate)
+	JwksProvider            string        `env:"JWKS_PROVIDER,expand"             envDefault:"file"` // Provider of the JWKS: 'file' (next/active/previous slots), 'directory' (all certificates in CERT_MOUNT_PATH) or 'kubernetes' (TLS secrets)
 	ServerConfig            ServerConfig
+	AdminConfig             AdminConfig
+	IssuerConfig            IssuerConfig
+	RealmConfig             RealmConfig
+	IntrospectionConfig     IntrospectionConfig
+	MetadataSigningConfig   MetadataSigningConfig
 	JwksConfig              JwksFileConfig
+	KubernetesConfig        JwksKubernetesConfig
 }
 
 type ServerConfig struct {
-	Port     int    `env:"SERVER_PORT,expand"   envDefault:"8081"`    // Port the server should listen on
-	BasePath string `env:"API_BASE_PATH,expand" envDefault:"/api/v1"` // Base path of the API
+	Port                   int           `env:"SERVER_PORT,expand"               envDefault:"8081"`    // Port the server should listen on
+	BasePath               string        `env:"API_BASE_PATH,expand"             envDefault:"/api/v1"` // Base path of the API
+	CacheMaxAge            time.Duration `env:"CACHE_MAX_AGE,expand"             envDefault:"5m"`      // Time the JWKS and discovery responses may be cached by clients. If 0 clients have to revalidate every response
+	CacheMaxAgeNextKey     time.Duration `env:"CACHE_MAX_AGE_NEXT_KEY,expand"    envDefault:"1m"`      // Time the responses may be cached while a next key is published, so clients pick up the rotation quickly
+	DiscoveryMetadataFile  string        `env:"DISCOVERY_METADATA_FILE,expand"   envDefault:""`        // Path to a JSON file with the discovery metadata per realm ('*' for every realm). '{issuer}' is replaced by the issuer URL of the realm
+	HealthMaxMissedReloads int           `env:"HEALTH_MAX_MISSED_RELOADS,expand" envDefault:"3"`       // Number of reload intervals without successful reload after which the service is not ready anymore
+	TLS                    TLSConfig
+}
+
+type AdminConfig struct {
+	Port      int    `env:"ADMIN_PORT,expand"       envDefault:"0"`         // Port of the admin API. If 0, the admin API is disabled
+	Host      string `env:"ADMIN_HOST,expand"       envDefault:"127.0.0.1"` // Address the admin API listens on. By default it is only reachable from within the pod (e.g. with kubectl port-forward)
+	TokenFile string `env:"ADMIN_TOKEN_FILE,expand" envDefault:""`          // Path to a file containing the bearer token of the admin API. If empty, the admin API is not authenticated
+}
+
+type TLSConfig struct {
+	CertFile       string        `env:"SERVER_TLS_CERT_FILE,expand"       envDefault:""`        // Path to the PEM encoded serving certificate. If empty, the server listens on plain HTTP
+	KeyFile        string        `env:"SERVER_TLS_KEY_FILE,expand"        envDefault:""`        // Path to the PEM encoded private key of the serving certificate
+	MinVersion     string        `env:"SERVER_TLS_MIN_VERSION,expand"     envDefault:"1.2"`     // Minimum TLS version: '1.2' or '1.3'
+	CipherSuites   []string      `env:"SERVER_TLS_CIPHER_SUITES,expand"   envDefault:""`        // Names of the allowed TLS 1.2 cipher suites (e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256). If empty, the Go defaults are used
+	ReloadInterval time.Duration `env:"SERVER_TLS_RELOAD_INTERVAL,expand" envDefault:"1m"`      // Interval in which the serving certificate is reloaded from disk. If 0 it is never reloaded
+	ClientCAFile   string        `env:"SERVER_TLS_CLIENT_CA_FILE,expand"  envDefault:""`        // Path to the PEM encoded CA bundle client certificates are verified against. If empty, no client certificate is requested
+	ClientAuth     string        `env:"SERVER_TLS_CLIENT_AUTH,expand"     envDefault:"require"` // Client certificate policy if a CA bundle is set: 'require' or 'verify-if-given'
+}
+
+type IssuerConfig struct {
+	Mode             string            `env:"ISSUER_MODE,expand"       envDefault:"static"`      // How the issuer URL is determined: 'static' (ISSUER_URL and REALM_ISSUER_URLS) or 'header' (forwarded headers of trusted proxies, falls back to the static URLs)
+	IssuerURL        string            `env:"ISSUER_URL,expand"        envDefault:""`            // Canonical base URL of the issuer (e.g. https://gateway.example.com). PATH_PREFIX and '/auth/realms/<realm>' are appended
+	RealmIssuerURLs  map[string]string `env:"REALM_ISSUER_URLS,expand" envKeyValSeparator:"="`   // Canonical base URLs of the issuer of specific realms (e.g. 'realm-a=https://a.example.com,realm-b=https://b.example.com'). Other realms use ISSUER_URL
+	TrustedProxies   []string          `env:"TRUSTED_PROXIES,expand"   envDefault:""`            // IPs or CIDRs of the proxies whose forwarded headers are trusted in 'header' mode. If empty, the headers are ignored and ISSUER_URL or REALM_ISSUER_URLS is used
+	ForwardedHeaders string            `env:"FORWARDED_HEADERS,expand" envDefault:"x-forwarded"` // Forwarded headers set by the trusted proxies in 'header' mode: 'x-forwarded' (X-Forwarded-Host, -Proto, -Port and -Prefix) or 'forwarded' (RFC 7239). The headers of the other kind are ignored
+	AllowedHosts     []string          `env:"ALLOWED_HOSTS,expand"     envDefault:""`            // Hosts that may be forwarded by a trusted proxy. '*.example.com' allows every subdomain. Required in 'header' mode
+}
+
+type RealmConfig struct {
+	Realms     []string `env:"REALMS,expand"      envDefault:""` // Names of the served realms. '*' accepts every realm. If neither REALMS nor REALMS_FILE is set, only 'default' is served. Realms with keys of their own are served in any case
+	RealmsFile string   `env:"REALMS_FILE,expand" envDefault:""` // Path to a file containing the names of the served realms, one per line
+}
+
+type IntrospectionConfig struct {
+	ClientsFile string `env:"INTROSPECTION_CLIENTS_FILE,expand" envDefault:""` // Path to a file with the credentials of the clients that may introspect tokens, one 'client_id:client_secret' per line. If empty, the introspection endpoint is disabled
+}
+
+type MetadataSigningConfig struct {
+	MountedPath    string        `env:"METADATA_SIGNING_MOUNT_PATH,expand"      envDefault:""`        // Path to the directory of the metadata signing key. If empty, the metadata is not signed
+	CertFileName   string        `env:"METADATA_SIGNING_CERT_FILE,expand"       envDefault:"tls.crt"` // Name of the certificate file of the metadata signing key, which is sent in the 'x5c' header
+	KeyFileName    string        `env:"METADATA_SIGNING_KEY_FILE,expand"        envDefault:"tls.key"` // Name of the private key file of the metadata signing key
+	KidFileName    string        `env:"METADATA_SIGNING_KID_FILE,expand"        envDefault:"tls.kid"` // Name of the key ID file of the metadata signing key. An optional '.alg' file next to it contains the signing algorithm
+	Alg            string        `env:"METADATA_SIGNING_ALG,expand"             envDefault:""`        // Signing algorithm if no '.alg' file exists. If empty it is derived from the key
+	UpdateInterval int           `env:"METADATA_SIGNING_UPDATE_INTERVAL,expand" envDefault:"10"`      // Interval in seconds in which the metadata signing key is reloaded. If 0 it is never reloaded
+	TokenLifetime  time.Duration `env:"METADATA_SIGNING_TOKEN_LIFETIME,expand"  envDefault:"24h"`     // Lifetime of the signed metadata. It is signed again after half of its lifetime
 }
 
 type JwksFileConfig struct {
-	UpdateInterval     int    `env:"CERT_UPDATE_INTERVAL,expand"     envDefault:"10"`           // Interval in seconds in which the certificates should be updated. If 0 scheduler is deactivated at all
-	MountedPath        string `env:"CERT_MOUNT_PATH,expand,required"`                           // Path to the directory where the certificates are mounted
-	CertFileNameNext   string `env:"CERT_FILE_NEXT,expand"           envDefault:"next-tls.crt"` // Name of the certificate file that should be used in the next rotation
-	KidFileNameNext    string `env:"KID_FILE_NEXT,expand"            envDefault:"next-tls.kid"` // Name of the key ID file that should be used in the next rotation
-	CertFileNameActive string `env:"CERT_FILE_ACTIVE,expand"         envDefault:"tls.crt"`      // Name of the certificate file that should be used currently
-	KidFileNameActive  string `env:"KID_FILE_ACTIVE,expand"          envDefault:"tls.kid"`      // Name of the key ID file that should be used currently
-	CertFileNamePrev   string `env:"CERT_FILE_PREV,expand"           envDefault:"prev-tls.crt"` // Name of the certificate file that should be used to verify the signature of JWTs that were signed with a key that is not the current one
-	KidFileNamePrev    string `env:"KID_FILE_PREV,expand"            envDefault:"prev-tls.kid"` // Name of the key ID file that should be used to verify the signature of JWTs that were signed with a key that is not the current one
+	UpdateInterval     int               `env:"CERT_UPDATE_INTERVAL,expand"    envDefault:"10"`            // Interval in seconds in which the certificates should be updated. If 0 scheduler is deactivated at all
+	MountedPath        string            `env:"CERT_MOUNT_PATH,expand"`                                    // Path to the directory where the certificates are mounted. Required for the 'file' and 'directory' provider
+	CertFileNameNext   string            `env:"CERT_FILE_NEXT,expand"          envDefault:"next-tls.crt"`  // Name of the certificate file that should be used in the next rotation
+	KidFileNameNext    string            `env:"KID_FILE_NEXT,expand"           envDefault:"next-tls.kid"`  // Name of the key ID file that should be used in the next rotation
+	CertFileNameActive string            `env:"CERT_FILE_ACTIVE,expand"        envDefault:"tls.crt"`       // Name of the certificate file that should be used currently
+	KidFileNameActive  string            `env:"KID_FILE_ACTIVE,expand"         envDefault:"tls.kid"`       // Name of the key ID file that should be used currently
+	CertFileNamePrev   string            `env:"CERT_FILE_PREV,expand"          envDefault:"prev-tls.crt"`  // Name of the certificate file that should be used to verify the signature of JWTs that were signed with a key that is not the current one
+	KidFileNamePrev    string            `env:"KID_FILE_PREV,expand"           envDefault:"prev-tls.kid"`  // Name of the key ID file that should be used to verify the signature of JWTs that were signed with a key that is not the current one
+	NextOptional       bool              `env:"CERT_NEXT_OPTIONAL,expand"      envDefault:"false"`         // Whether the next certificate may be missing (e.g. on a fresh installation)
+	PrevOptional       bool              `env:"CERT_PREV_OPTIONAL,expand"      envDefault:"false"`         // Whether the previous certificate may be missing (e.g. on a fresh installation)
+	Alg                string            `env:"CERT_ALG,expand"                envDefault:""`              // Signing algorithm of the certificates if no '.alg' file exists next to the key ID file. If empty it is derived from the key
+	ReloadMode         string            `env:"CERT_RELOAD_MODE,expand"        envDefault:"poll"`          // Reload mode of the certificates: 'poll' (every CERT_UPDATE_INTERVAL seconds) or 'watch' (on file system notifications, falls back to 'poll' if watching is unavailable)
+	WatchDebounce      time.Duration     `env:"CERT_WATCH_DEBOUNCE,expand"     envDefault:"500ms"`         // Time without further changes after which the certificates are reloaded in 'watch' mode
+	ActiveMarkerFile   string            `env:"CERT_ACTIVE_MARKER_FILE,expand" envDefault:"active"`        // Directory provider only: name of the file containing the key ID or file base name of the active certificate
+	ActiveKid          string            `env:"CERT_ACTIVE_KID,expand"         envDefault:""`              // Directory provider only: key ID or file base name of the active certificate. Takes precedence over the marker file
+	ExpiryWarnings     []time.Duration   `env:"CERT_EXPIRY_WARNINGS,expand"    envDefault:"720h,168h,24h"` // Remaining validity periods of a certificate at which a warning is logged once
+	ExcludeExpired     bool              `env:"CERT_EXCLUDE_EXPIRED,expand"    envDefault:"false"`         // Whether expired certificates are excluded from the JWKS
+	RealmMountPaths    map[string]string `env:"REALM_CERT_MOUNT_PATHS,expand"  envKeyValSeparator:"="`     // Paths to the directories of the certificates of specific realms (e.g. 'realm-a=/certs/a,realm-b=/certs/b'). The file names are the same as in CERT_MOUNT_PATH
+
+	Realm string // Realm whose certificates are provided, empty if the certificates are provided for every realm
+}
+
+type JwksKubernetesConfig struct {
+	Namespace              string            `env:"K8S_NAMESPACE,expand"            envDefault:""`              // Namespace of the secrets. If empty, the namespace of the pod is used
+	SecretNameNext         string            `env:"K8S_SECRET_NEXT,expand"          envDefault:""`              // Name of the TLS secret that should be used in the next rotation. If empty, no next key is published
+	SecretNameActive       string            `env:"K8S_SECRET_ACTIVE,expand"        envDefault:""`              // Name of the TLS secret that should be used currently
+	SecretNamePrev         string            `env:"K8S_SECRET_PREV,expand"          envDefault:""`              // Name of the TLS secret that was used previously. If empty, no previous key is published
+	KidKey                 string            `env:"K8S_SECRET_KID_KEY,expand"       envDefault:"tls.kid"`       // Key of the key ID in the data of the secrets
+	AlgKey                 string            `env:"K8S_SECRET_ALG_KEY,expand"       envDefault:"tls.alg"`       // Key of the optional signing algorithm in the data of the secrets
+	Alg                    string            `env:"CERT_ALG,expand"                 envDefault:""`              // Signing algorithm of the certificates if the secret does not contain the algorithm. If empty it is derived from the key
+	ExpiryWarnings         []time.Duration   `env:"CERT_EXPIRY_WARNINGS,expand"     envDefault:"720h,168h,24h"` // Remaining validity periods of a certificate at which a warning is logged once
+	ExcludeExpired         bool              `env:"CERT_EXCLUDE_EXPIRED,expand"     envDefault:"false"`         // Whether expired certificates are excluded from the JWKS
+	RealmSecretNamesNext   map[string]string `env:"K8S_REALM_SECRETS_NEXT,expand"   envKeyValSeparator:"="`     // Names of the next TLS secrets of specific realms (e.g. 'realm-a=next-a,realm-b=next-b')
+	RealmSecretNamesActive map[string]string `env:"K8S_REALM_SECRETS_ACTIVE,expand" envKeyValSeparator:"="`     // Names of the active TLS secrets of specific realms. Every realm with an active secret is served its own keys
+	RealmSecretNamesPrev   map[string]string `env:"K8S_REALM_SECRETS_PREV,expand"   envKeyValSeparator:"="`     // Names of the previous TLS secrets of specific realms
+
+	Realm string // Realm whose secrets are provided, empty if the secrets are provided for every realm
 }
 
+const (
+	ClientAuthRequire       = "require"
+	ClientAuthVerifyIfGiven = "verify-if-given"
+)
+
+const (
+	IssuerModeStatic = "static"
+	IssuerModeHeader = "header"
+)
+
+const (
+	ForwardedHeadersXForwarded = "x-forwarded"
+	ForwardedHeadersForwarded  = "forwarded"
+)
+
+const (
+	ReloadModePoll  = "poll"
+	ReloadModeWatch = "watch"
+)
+
+const (
+	ProviderFile       = "file"
+	ProviderDirectory  = "directory"
+	ProviderKubernetes = "kubernetes"
+)
+
 type Type int
 
 const (
@@ -42,6 +156,28 @@ const (
 	Previous
 )
 
+func (t Type) String() string {
+	switch t {
+	case Next:
+		return "next"
+	case Active:
+		return "active"
+	case Previous:
+		return "previous"
+	}
+	return "unknown"
+}
+
+// IsEnabled returns whether the admin API should be served.
+func (c *AdminConfig) IsEnabled() bool {
+	return c.Port != 0
+}
+
+// IsEnabled returns whether the server should listen on HTTPS.
+func (c *TLSConfig) IsEnabled() bool {
+	return c.CertFile != ""
+}
+
 func (c *JwksFileConfig) GetCertFile(jwksType Type) string {
 	switch jwksType {
 	case Next:
@@ -65,3 +201,105 @@ func (c *JwksFileConfig) GetKidFile(jwksType Type) string {
 	}
 	return ""
 }
+
+// IsOptional returns whether the certificate of the given type may be missing. The active certificate is
+// always mandatory.
+func (c *JwksFileConfig) IsOptional(jwksType Type) bool {
+	switch jwksType {
+	case Next:
+		return c.NextOptional
+	case Previous:
+		return c.PrevOptional
+	case Active:
+		return false
+	}
+	return false
+}
+
+// GetAlgFile returns the path of the optional algorithm file, which is located next to the key ID file
+// and has the same name with the extension '.alg' (e.g. 'tls.kid' -> 'tls.alg').
+func (c *JwksFileConfig) GetAlgFile(jwksType Type) string {
+	kidFile := c.GetKidFile(jwksType)
+	if kidFile == "" {
+		return ""
+	}
+	return strings.TrimSuffix(kidFile, path.Ext(kidFile)) + ".alg"
+}
+
+// GetCertFile returns the path of the certificate file of the metadata signing key.
+func (c *MetadataSigningConfig) GetCertFile() string {
+	return path.Join(c.MountedPath, c.CertFileName)
+}
+
+// GetKeyFile returns the path of the private key file of the metadata signing key.
+func (c *MetadataSigningConfig) GetKeyFile() string {
+	return path.Join(c.MountedPath, c.KeyFileName)
+}
+
+// GetKidFile returns the path of the key ID file of the metadata signing key.
+func (c *MetadataSigningConfig) GetKidFile() string {
+	return path.Join(c.MountedPath, c.KidFileName)
+}
+
+// GetAlgFile returns the path of the optional algorithm file, which is located next to the key ID file.
+func (c *MetadataSigningConfig) GetAlgFile() string {
+	kidFile := c.GetKidFile()
+	return strings.TrimSuffix(kidFile, path.Ext(kidFile)) + ".alg"
+}
+
+func (c *JwksKubernetesConfig) GetSecretName(jwksType Type) string {
+	switch jwksType {
+	case Next:
+		return c.SecretNameNext
+	case Active:
+		return c.SecretNameActive
+	case Previous:
+		return c.SecretNamePrev
+	}
+	return ""
+}
+
+// GetWatchDirs returns the distinct directories containing the certificate and key ID files.
+func (c *JwksFileConfig) GetWatchDirs() []string {
+	dirs := make([]string, 0, 1)
+	for _, jwksType := range []Type{Next, Active, Previous} {
+		for _, file := range []string{c.GetCertFile(jwksType), c.GetKidFile(jwksType)} {
+			if dir := path.Dir(file); !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// Realms returns the sorted realms with a separate certificate directory.
+func (c *JwksFileConfig) Realms() []string {
+	return slices.Sorted(maps.Keys(c.RealmMountPaths))
+}
+
+// ForRealm returns the configuration of the certificates of the realm, which only differs in the directory.
+func (c *JwksFileConfig) ForRealm(realm string) *JwksFileConfig {
+	realmConfig := *c
+	realmConfig.MountedPath = c.RealmMountPaths[realm]
+	realmConfig.RealmMountPaths = nil
+	realmConfig.Realm = realm
+	return &realmConfig
+}
+
+// Realms returns the sorted realms with separate secrets.
+func (c *JwksKubernetesConfig) Realms() []string {
+	return slices.Sorted(maps.Keys(c.RealmSecretNamesActive))
+}
+
+// ForRealm returns the configuration of the secrets of the realm, which only differs in the secret names.
+func (c *JwksKubernetesConfig) ForRealm(realm string) *JwksKubernetesConfig {
+	realmConfig := *c
+	realmConfig.SecretNameNext = c.RealmSecretNamesNext[realm]
+	realmConfig.SecretNameActive = c.RealmSecretNamesActive[realm]
+	realmConfig.SecretNamePrev = c.RealmSecretNamesPrev[realm]
+	realmConfig.RealmSecretNamesNext = nil
+	realmConfig.RealmSecretNamesActive = nil
+	realmConfig.RealmSecretNamesPrev = nil
+	realmConfig.Realm = realm
+	return &realmConfig
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package introspection
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Clients contains the credentials of the clients that may introspect tokens.
+type Clients struct {
+	secrets map[string][sha256.Size]byte
+}
+
+// NewClients reads the credentials of the clients from a file with one 'client_id:client_secret' per line. Empty
+// lines and lines starting with '#' are ignored. If file is empty, no client is configured and the introspection
+// endpoint is disabled.
+func NewClients(file string) (*Clients, error) {
+	clients := &Clients{secrets: make(map[string][sha256.Size]byte)}
+	if file == "" {
+		return clients, nil
+	}
+
+	fileByteArray, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read introspection clients file: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(fileByteArray))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		clientID, clientSecret, found := strings.Cut(line, ":")
+		if !found || clientID == "" || clientSecret == "" {
+			return nil, fmt.Errorf("invalid introspection client in line %d, expected 'client_id:client_secret'",
+				lineNumber)
+		}
+		clients.secrets[clientID] = sha256.Sum256([]byte(clientSecret))
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read introspection clients file: %w", err)
+	}
+
+	return clients, nil
+}
+
+// Enabled returns whether any client is configured.
+func (c *Clients) Enabled() bool {
+	return c != nil && len(c.secrets) > 0
+}
+
+// Authenticate returns whether the secret is the configured secret of the client. The secrets are compared in
+// constant time.
+func (c *Clients) Authenticate(clientID string, clientSecret string) bool {
+	if !c.Enabled() {
+		return false
+	}
+
+	// the hashes are compared, so the comparison does not reveal the length of the secret
+	secret, exists := c.secrets[clientID]
+	hash := sha256.Sum256([]byte(clientSecret))
+	return subtle.ConstantTimeCompare(secret[:], hash[:]) == 1 && exists
+}
+
+// ParseBasicAuth returns the client credentials of an 'Authorization' header of the 'Basic' scheme. As required by
+// RFC 6749 section 2.3.1, the client ID and secret are form-urlencoded before they are encoded with base64.
+func ParseBasicAuth(authorization string) (string, string, bool) {
+	scheme, credentials, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Basic") {
+		return "", "", false
+	}
+
+	credentialsByteArray, err := base64.StdEncoding.DecodeString(strings.TrimSpace(credentials))
+	if err != nil {
+		return "", "", false
+	}
+
+	encodedClientID, encodedClientSecret, found := strings.Cut(string(credentialsByteArray), ":")
+	if !found {
+		return "", "", false
+	}
+	clientID, err := url.QueryUnescape(encodedClientID)
+	if err != nil {
+		return "", "", false
+	}
+	clientSecret, err := url.QueryUnescape(encodedClientSecret)
+	if err != nil {
+		return "", "", false
+	}
+	return clientID, clientSecret, true
+}
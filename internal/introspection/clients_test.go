@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package introspection_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"issuer-service-go/internal/introspection"
+	"issuer-service-go/internal/validation"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClients(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		err     bool
+		enabled bool
+	}{
+		{
+			name: "introspection is disabled if no file is configured",
+		},
+		{
+			name:    "clients from the file",
+			file:    "./clients_testdata/clients",
+			enabled: true,
+		},
+		{
+			name: "error if a line has no secret",
+			file: "./clients_testdata/clients-invalid",
+			err:  true,
+		},
+		{
+			name: "error if the file does not exist",
+			file: "./clients_testdata/missing",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients, err := introspection.NewClients(tt.file)
+			assert.Equalf(t, tt.err, err != nil, "expected error: %v, got: %v", tt.err, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tt.enabled, clients.Enabled())
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	clients, err := introspection.NewClients("./clients_testdata/clients")
+	if err != nil {
+		t.Fatalf("failed to read clients: %v", err)
+	}
+
+	assert.True(t, clients.Authenticate("resource-server", "s3cr3t"))
+	assert.True(t, clients.Authenticate("other-client", "pass:with:colons"), "secrets may contain colons")
+	assert.False(t, clients.Authenticate("resource-server", "wrong"))
+	assert.False(t, clients.Authenticate("resource-server", ""))
+	assert.False(t, clients.Authenticate("unknown", "s3cr3t"))
+
+	var disabled *introspection.Clients
+	assert.False(t, disabled.Enabled())
+	assert.False(t, disabled.Authenticate("resource-server", "s3cr3t"))
+}
+
+func TestParseBasicAuth(t *testing.T) {
+	encode := func(credentials string) string {
+		return base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+
+	tests := []struct {
+		name                 string
+		authorization        string
+		ok                   bool
+		expectedClientID     string
+		expectedClientSecret string
+	}{
+		{
+			name:                 "basic credentials",
+			authorization:        "Basic " + encode("resource-server:s3cr3t"),
+			ok:                   true,
+			expectedClientID:     "resource-server",
+			expectedClientSecret: "s3cr3t",
+		},
+		{
+			name:                 "credentials are form-urlencoded",
+			authorization:        "basic " + encode("resource%3Aserver:s3cr3t%2B"),
+			ok:                   true,
+			expectedClientID:     "resource:server",
+			expectedClientSecret: "s3cr3t+",
+		},
+		{
+			name:          "other scheme",
+			authorization: "Bearer eyJ",
+		},
+		{
+			name:          "no colon",
+			authorization: "Basic " + encode("resource-server"),
+		},
+		{
+			name:          "invalid base64",
+			authorization: "Basic !",
+		},
+		{
+			name: "no header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientID, clientSecret, ok := introspection.ParseBasicAuth(tt.authorization)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expectedClientID, clientID)
+			assert.Equal(t, tt.expectedClientSecret, clientSecret)
+		})
+	}
+}
+
+func TestNewResponse(t *testing.T) {
+	diagnosis := &validation.Diagnosis{
+		Valid: true,
+		Claims: map[string]any{
+			"sub":                "1234",
+			"exp":                json.Number("1735689600"),
+			"azp":                "gateway-client",
+			"preferred_username": "service-account",
+			"typ":                "Bearer",
+			"scope":              "openid profile",
+		},
+	}
+
+	response := introspection.NewResponse(diagnosis)
+	assert.Equal(t, true, response["active"])
+	assert.Equal(t, "gateway-client", response["client_id"])
+	assert.Equal(t, "service-account", response["username"])
+	assert.Equal(t, "Bearer", response["token_type"])
+	assert.Equal(t, "openid profile", response["scope"])
+
+	responseByteArray, err := json.Marshal(response)
+	if err != nil {
+		t.Fatalf("failed to marshal response: %v", err)
+	}
+	assert.Contains(t, string(responseByteArray), `"exp":1735689600`, "numeric dates must not be reformatted")
+	assert.NotContains(t, diagnosis.Claims, "active", "the claims of the diagnosis must not be modified")
+
+	diagnosis.Claims["client_id"] = "explicit-client"
+	assert.Equal(t, "explicit-client", introspection.NewResponse(diagnosis)["client_id"])
+
+	diagnosis.Valid = false
+	assert.Equal(t, map[string]any{"active": false}, introspection.NewResponse(diagnosis))
+}
@@ -0,0 +1,37 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package introspection
+
+import (
+	"issuer-service-go/internal/validation"
+	"maps"
+)
+
+// NewResponse creates the introspection response of RFC 7662 section 2.2. As Keycloak does, the response of an
+// active token contains all claims of the token. 'client_id', 'username' and 'token_type' are derived from the 'azp',
+// 'preferred_username' and 'typ' claims if the token does not contain them. The response of an inactive token only
+// contains 'active', so it does not reveal why the token is inactive.
+func NewResponse(diagnosis *validation.Diagnosis) map[string]any {
+	if !diagnosis.Valid {
+		return map[string]any{"active": false}
+	}
+
+	response := maps.Clone(diagnosis.Claims)
+	if response == nil {
+		response = make(map[string]any)
+	}
+	setDefault(response, "client_id", response["azp"])
+	setDefault(response, "username", response["preferred_username"])
+	setDefault(response, "token_type", response["typ"])
+	response["active"] = true
+	return response
+}
+
+// setDefault sets the member of the response if it is not set yet and the value is not nil.
+func setDefault(response map[string]any, name string, value any) {
+	if _, exists := response[name]; !exists && value != nil {
+		response[name] = value
+	}
+}
@@ -6,6 +6,10 @@ package jwks
 
 //nolint:gosec // we have to provide 'x5t' in JWK so we are backwards-compatible
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/sha1"
 	"crypto/sha256"
@@ -13,28 +17,209 @@ import (
 	"encoding/base64"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"math/big"
 	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	KtyRSA = "RSA"
+	KtyEC  = "EC"
+	KtyOKP = "OKP"
+
+	crvEd25519 = "Ed25519"
+
+	// minRSAKeySize is the minimum size in bits of RSA keys, as required for RS and PS algorithms by RFC 7518.
+	minRSAKeySize = 2048
 )
 
-// Alg return currently always RS256.
-func Alg() string {
-	return "RS256"
+// Kty returns the JWK key type ("RSA", "EC" or "OKP") of the certificate's public key.
+func Kty(cert *x509.Certificate) (string, error) {
+	switch cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return KtyRSA, nil
+	case *ecdsa.PublicKey:
+		return KtyEC, nil
+	case ed25519.PublicKey:
+		return KtyOKP, nil
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", cert.PublicKey)
+	}
+}
+
+// Alg returns the default JWS algorithm derived from the certificate's public key.
+//
+// RSA keys use RS256 regardless of their size to stay backwards-compatible, EC keys use the ES algorithm that
+// matches their curve (ES256 for P-256, ES384 for P-384 and ES512 for P-521) and Ed25519 keys use EdDSA.
+func Alg(cert *x509.Certificate) (string, error) {
+	switch pubKey := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RS256", nil
+	case *ecdsa.PublicKey:
+		switch pubKey.Curve {
+		case elliptic.P256():
+			return "ES256", nil
+		case elliptic.P384():
+			return "ES384", nil
+		case elliptic.P521():
+			return "ES512", nil
+		}
+		return "", fmt.Errorf("unsupported elliptic curve %s", pubKey.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "EdDSA", nil
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", cert.PublicKey)
+	}
+}
+
+// ResolveAlg returns the JWS algorithm that should be advertised for the certificate.
+//
+// An explicit alg of the key (e.g. its '.alg' file) or the configured one takes precedence. If alg is empty, the
+// algorithm is derived from the public key (see Alg). Otherwise alg is validated against the public key: RSA keys
+// accept RS256, RS384, RS512, PS256, PS384 and PS512, EC keys only accept the algorithm matching their curve and
+// Ed25519 keys only accept EdDSA. RSA keys with less than 2048 bits are still served, but a warning is logged.
+func ResolveAlg(cert *x509.Certificate, alg string) (string, error) {
+	derivedAlg, err := Alg(cert)
+	if err != nil {
+		return "", err
+	}
+	if HasWeakKey(cert) {
+		log.Warn().Msgf("RSA key of certificate '%s' has less than %d bits, which RFC 7518 requires",
+			cert.Subject.CommonName, minRSAKeySize)
+	}
+
+	alg = strings.TrimSpace(alg)
+	if alg == "" {
+		return derivedAlg, nil
+	}
+
+	if _, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+		switch alg {
+		case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+			return alg, nil
+		}
+	} else if alg == derivedAlg {
+		return alg, nil
+	}
+
+	return "", fmt.Errorf("algorithm %s is not supported for %T", alg, cert.PublicKey)
+}
+
+// HasWeakKey returns whether the certificate has an RSA key with less than the 2048 bits that RFC 7518 requires
+// for the RS and PS algorithms.
+func HasWeakKey(cert *x509.Certificate) bool {
+	pubKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	return ok && pubKey.N.BitLen() < minRSAKeySize
+}
+
+// ParseCertificateChain parses all PEM encoded certificates.
+//
+// The certificates have to form a chain with the leaf certificate first, followed by the certificates
+// that issued it, as required for 'x5c' by RFC 7517 section 4.7. Other PEM blocks are ignored.
+//
+// Parameters:
+//
+//	certByteArray ([]byte): The PEM encoded certificates.
+//
+// Returns:
+//
+//	[]*x509.Certificate: The certificates with the leaf certificate first.
+//	error: An error if no certificate was found or the certificates do not form a chain.
+func ParseCertificateChain(certByteArray []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for block, rest := pem.Decode(certByteArray); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+
+	if len(chain) == 0 {
+		return nil, errors.New("failed to decode certificate PEM")
+	}
+
+	for i := 1; i < len(chain); i++ {
+		if err := chain[i-1].CheckSignatureFrom(chain[i]); err != nil {
+			return nil, fmt.Errorf(
+				"certificate %d (%s) is not issued by certificate %d (%s), the chain must start with the leaf: %w",
+				i-1, chain[i-1].Subject, i, chain[i].Subject, err,
+			)
+		}
+	}
+
+	return chain, nil
+}
+
+// ParsePrivateKey parses the first PEM encoded private key in PKCS #8, PKCS #1 (RSA) or SEC 1 (EC) form.
+//
+// Parameters:
+//
+//	keyByteArray ([]byte): The PEM encoded private key.
+//
+// Returns:
+//
+//	crypto.Signer: The private key.
+//	error: An error if no supported private key was found.
+func ParsePrivateKey(keyByteArray []byte) (crypto.Signer, error) {
+	for block, rest := pem.Decode(keyByteArray); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse private key: %w", err)
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("unsupported private key type %T", key)
+			}
+			return signer, nil
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		}
+	}
+	return nil, errors.New("failed to decode private key PEM")
+}
+
+// Hash returns the hash function of the JWS algorithm. EdDSA has no separate hash function and is not supported.
+func Hash(alg string) (crypto.Hash, error) {
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		return crypto.SHA256, nil
+	case "RS384", "PS384", "ES384":
+		return crypto.SHA384, nil
+	case "RS512", "PS512", "ES512":
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported algorithm '%s'", alg)
 }
 
 // X5c generates the X.509 certificate chain.
 //
-// The function takes an X.509 certificate as input and returns a slice of base64-encoded DER certificates.
+// The function takes the X.509 certificates of the chain as input and returns a slice of base64-encoded DER
+// certificates in the same order.
 //
 // Parameters:
 //
-//	cert (*x509.Certificate): The X.509 certificate from which to generate the certificate chain.
+//	chain (...*x509.Certificate): The X.509 certificates of the chain with the leaf certificate first.
 //
 // Returns:
 //
-//	[]string: A slice containing the base64-encoded DER representation of the certificate.
-func X5c(cert *x509.Certificate) []string {
-	return []string{base64.StdEncoding.EncodeToString(cert.Raw)}
+//	[]string: A slice containing the base64-encoded DER representation of the certificates.
+func X5c(chain ...*x509.Certificate) []string {
+	x5c := make([]string, 0, len(chain))
+	for _, cert := range chain {
+		x5c = append(x5c, base64.StdEncoding.EncodeToString(cert.Raw))
+	}
+	return x5c
 }
 
 // X5t generates the SHA-1 thumbprint of the given X.509 certificate.
@@ -115,3 +300,62 @@ func E(cert *x509.Certificate) (string, error) {
 	// Encode the exponent in Base64 URL encoding
 	return base64.RawURLEncoding.EncodeToString(eBytes), nil
 }
+
+// Crv returns the JWK curve name of the EC or OKP public key.
+func Crv(cert *x509.Certificate) (string, error) {
+	if _, ok := cert.PublicKey.(ed25519.PublicKey); ok {
+		return crvEd25519, nil
+	}
+
+	ecPubKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return "", errors.New("public key is not of type EC or OKP")
+	}
+
+	switch ecPubKey.Curve {
+	case elliptic.P256(), elliptic.P384(), elliptic.P521():
+		return ecPubKey.Curve.Params().Name, nil
+	}
+	return "", fmt.Errorf("unsupported elliptic curve %s", ecPubKey.Curve.Params().Name)
+}
+
+// X generates the x coordinate of the EC public key or the raw OKP public key in base64 URL encoding.
+func X(cert *x509.Certificate) (string, error) {
+	if edPubKey, ok := cert.PublicKey.(ed25519.PublicKey); ok {
+		return base64.RawURLEncoding.EncodeToString(edPubKey), nil
+	}
+
+	x, _, err := ecCoordinates(cert)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(x), nil
+}
+
+// Y generates the y coordinate of the EC public key in base64 URL encoding.
+func Y(cert *x509.Certificate) (string, error) {
+	_, y, err := ecCoordinates(cert)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(y), nil
+}
+
+// ecCoordinates returns the x and y coordinates of the EC public key, each padded
+// to the full size of the curve as required by RFC 7518 section 6.2.1.
+func ecCoordinates(cert *x509.Certificate) ([]byte, []byte, error) {
+	ecPubKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, nil, errors.New("public key is not of type EC")
+	}
+
+	ecdhPubKey, err := ecPubKey.ECDH()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid EC public key: %w", err)
+	}
+
+	// uncompressed point encoding: 0x04 || x || y
+	point := ecdhPubKey.Bytes()
+	size := (len(point) - 1) / 2
+	return point[1 : 1+size], point[1+size:], nil
+}
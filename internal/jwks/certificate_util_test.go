@@ -5,10 +5,14 @@
 package jwks_test
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/testutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -49,17 +53,186 @@ func TestAlg(t *testing.T) {
 			certPath:    testPath + "/cert.tls",
 			expectedAlg: "RS256",
 		},
+		{
+			description: "verify 'alg' is correctly detected and is ES256",
+			certPath:    testPath + "/ec-p256.tls",
+			expectedAlg: "ES256",
+		},
+		{
+			description: "verify 'alg' is correctly detected and is ES384",
+			certPath:    testPath + "/ec-p384.tls",
+			expectedAlg: "ES384",
+		},
+		{
+			description: "verify 'alg' is correctly detected and is ES512",
+			certPath:    testPath + "/ec-p521.tls",
+			expectedAlg: "ES512",
+		},
+		{
+			description: "verify 'alg' is correctly detected and is EdDSA",
+			certPath:    testPath + "/ed25519.tls",
+			expectedAlg: "EdDSA",
+		},
+	}
+
+	for _, test := range tests {
+		cert := loadCertificate(t, test.certPath)
+
+		// when
+		alg, _ := jwks.Alg(cert)
+
+		// then
+		assert.Equalf(t, test.expectedAlg, alg, test.description)
+	}
+}
+
+func TestResolveAlg(t *testing.T) {
+	// given
+	tests := []struct {
+		description string
+		certPath    string
+		alg         string
+		expectedAlg string
+		err         bool
+	}{
+		{
+			description: "verify 'alg' is derived from the RSA key if not configured",
+			certPath:    testPath + "/cert.tls",
+			alg:         "",
+			expectedAlg: "RS256",
+		},
+		{
+			description: "verify RS512 is accepted for RSA keys",
+			certPath:    testPath + "/cert.tls",
+			alg:         "RS512",
+			expectedAlg: "RS512",
+		},
+		{
+			description: "verify PS384 is accepted for RSA keys",
+			certPath:    testPath + "/cert.tls",
+			alg:         " PS384\n",
+			expectedAlg: "PS384",
+		},
+		{
+			description: "verify RSA keys with less than 2048 bits are still served",
+			certPath:    testPath + "/rsa-1024.tls",
+			alg:         "",
+			expectedAlg: "RS256",
+		},
+		{
+			description: "verify RSA keys with less than 2048 bits are still served with an explicit algorithm",
+			certPath:    testPath + "/rsa-1024.tls",
+			alg:         "PS512",
+			expectedAlg: "PS512",
+		},
+		{
+			description: "verify ES256 is rejected for RSA keys",
+			certPath:    testPath + "/cert.tls",
+			alg:         "ES256",
+			err:         true,
+		},
+		{
+			description: "verify 'alg' is derived from the EC curve if not configured",
+			certPath:    testPath + "/ec-p384.tls",
+			alg:         "",
+			expectedAlg: "ES384",
+		},
+		{
+			description: "verify an algorithm not matching the EC curve is rejected",
+			certPath:    testPath + "/ec-p384.tls",
+			alg:         "ES256",
+			err:         true,
+		},
+		{
+			description: "verify EdDSA is accepted for Ed25519 keys",
+			certPath:    testPath + "/ed25519.tls",
+			alg:         "EdDSA",
+			expectedAlg: "EdDSA",
+		},
+		{
+			description: "verify RS256 is rejected for Ed25519 keys",
+			certPath:    testPath + "/ed25519.tls",
+			alg:         "RS256",
+			err:         true,
+		},
 	}
 
 	for _, test := range tests {
+		cert := loadCertificate(t, test.certPath)
+
 		// when
-		alg := jwks.Alg()
+		alg, err := jwks.ResolveAlg(cert, test.alg)
 
 		// then
+		assert.Equalf(t, test.err, err != nil, test.description)
 		assert.Equalf(t, test.expectedAlg, alg, test.description)
 	}
 }
 
+func TestHasWeakKey(t *testing.T) {
+	tests := []struct {
+		description string
+		keySize     int
+		expected    bool
+	}{
+		{description: "RSA key with 2047 bits is weak", keySize: 2047, expected: true},
+		{description: "RSA key with 2048 bits is not weak", keySize: 2048, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			key, err := rsa.GenerateKey(rand.Reader, test.keySize)
+			if err != nil {
+				t.Fatalf("failed to generate RSA key: %v", err)
+			}
+			cert := testutil.NewCertificate(t, testutil.CertificateOptions{Key: key}).Cert
+
+			assert.Equal(t, test.expected, jwks.HasWeakKey(cert))
+			alg, err := jwks.ResolveAlg(cert, "")
+			assert.NoError(t, err)
+			assert.Equal(t, "RS256", alg)
+		})
+	}
+
+	assert.True(t, jwks.HasWeakKey(loadCertificate(t, testPath+"/rsa-1024.tls")))
+	assert.False(t, jwks.HasWeakKey(loadCertificate(t, testPath+"/ec-p256.tls")))
+}
+
+func TestKty(t *testing.T) {
+	// given
+	tests := []struct {
+		description string
+		certPath    string
+		expectedKty string
+	}{
+		{
+			description: "verify 'kty' is correctly detected for RSA keys",
+			certPath:    testPath + "/cert.tls",
+			expectedKty: "RSA",
+		},
+		{
+			description: "verify 'kty' is correctly detected for EC keys",
+			certPath:    testPath + "/ec-p256.tls",
+			expectedKty: "EC",
+		},
+		{
+			description: "verify 'kty' is correctly detected for Ed25519 keys",
+			certPath:    testPath + "/ed25519.tls",
+			expectedKty: "OKP",
+		},
+	}
+
+	for _, test := range tests {
+		cert := loadCertificate(t, test.certPath)
+
+		// when
+		kty, _ := jwks.Kty(cert)
+
+		// then
+		assert.Equalf(t, test.expectedKty, kty, test.description)
+	}
+}
+
 func TestX5c(t *testing.T) {
 	// given
 	tests := []struct {
@@ -89,6 +262,79 @@ func TestX5c(t *testing.T) {
 	}
 }
 
+func TestX5cWithChain(t *testing.T) {
+	// given
+	certPEM, err := os.ReadFile(testPath + "/chain.tls")
+	if err != nil {
+		t.Fatalf("failed to read certificate file: %v", err)
+	}
+	x5cVerifier, err := os.ReadFile(testPath + "/chain-x5c")
+	if err != nil {
+		t.Fatalf("failed to read x5c verifier file: %v", err)
+	}
+
+	chain, err := jwks.ParseCertificateChain(certPEM)
+	if err != nil {
+		t.Fatalf("failed to parse certificate chain: %v", err)
+	}
+
+	// when
+	x5c := jwks.X5c(chain...)
+
+	// then
+	assert.Equal(t, strings.Fields(string(x5cVerifier)), x5c, "verify 'x5c' contains the full chain")
+}
+
+func TestParseCertificateChain(t *testing.T) {
+	// given
+	tests := []struct {
+		description string
+		certPath    string
+		chainLength int
+		err         bool
+	}{
+		{
+			description: "verify a single certificate is parsed",
+			certPath:    testPath + "/cert.tls",
+			chainLength: 1,
+		},
+		{
+			description: "verify leaf, intermediate and root certificate are parsed",
+			certPath:    testPath + "/chain.tls",
+			chainLength: 3,
+		},
+		{
+			description: "verify a chain not starting with the leaf is rejected",
+			certPath:    testPath + "/chain-unordered.tls",
+			err:         true,
+		},
+		{
+			description: "verify a chain with a missing intermediate is rejected",
+			certPath:    testPath + "/chain-incomplete.tls",
+			err:         true,
+		},
+		{
+			description: "verify a file without certificates is rejected",
+			certPath:    testPath + "/x5c",
+			err:         true,
+		},
+	}
+
+	for _, test := range tests {
+		certPEM, err := os.ReadFile(test.certPath)
+		if err != nil {
+			t.Fatalf("failed to read certificate file: %v", err)
+		}
+
+		// when
+		chain, err := jwks.ParseCertificateChain(certPEM)
+
+		// then
+		assert.Equalf(t, test.err, err != nil, test.description)
+		assert.Lenf(t, chain, test.chainLength, test.description)
+	}
+}
+
 func TestX5t(t *testing.T) {
 	// given
 	tests := []struct {
@@ -213,3 +459,97 @@ func TestPublicKey(t *testing.T) {
 		assert.Equalf(t, test.publicKey, exponent, test.description)
 	}
 }
+
+func TestCrv(t *testing.T) {
+	// given
+	tests := []struct {
+		description string
+		certPath    string
+		crv         string
+		err         bool
+	}{
+		{
+			description: "verify 'crv' is correctly detected for P-256",
+			certPath:    testPath + "/ec-p256.tls",
+			crv:         "P-256",
+		},
+		{
+			description: "verify 'crv' is correctly detected for P-384",
+			certPath:    testPath + "/ec-p384.tls",
+			crv:         "P-384",
+		},
+		{
+			description: "verify 'crv' is correctly detected for P-521",
+			certPath:    testPath + "/ec-p521.tls",
+			crv:         "P-521",
+		},
+		{
+			description: "verify 'crv' is correctly detected for Ed25519",
+			certPath:    testPath + "/ed25519.tls",
+			crv:         "Ed25519",
+		},
+		{
+			description: "verify 'crv' can not be generated for RSA keys",
+			certPath:    testPath + "/cert.tls",
+			err:         true,
+		},
+	}
+
+	for _, test := range tests {
+		cert := loadCertificate(t, test.certPath)
+
+		// when
+		crv, err := jwks.Crv(cert)
+
+		// then
+		assert.Equalf(t, test.err, err != nil, test.description)
+		assert.Equalf(t, test.crv, crv, test.description)
+	}
+}
+
+func TestXY(t *testing.T) {
+	// given
+	tests := []struct {
+		description string
+		certPath    string
+		x           string
+		y           string
+	}{
+		{
+			description: "verify 'x' and 'y' are correctly generated for P-256",
+			certPath:    testPath + "/ec-p256.tls",
+			x:           "H8NIA_rLZ_MJ_HPLSWuazwQ34vaE0yimTONFkPSNC6A",
+			y:           "Nbz0Nm_3nKBVsnGxt6waI4_mPHqy8fJrNezjuyNiWII",
+		},
+		{
+			description: "verify 'x' and 'y' are correctly generated for P-384",
+			certPath:    testPath + "/ec-p384.tls",
+			x:           "h0Mofuq4RVYXYsqBGiP0KIEC38rW2qbGJ8hsZ5phi05PQb4rTU6MJHB9eUK0TdmN",
+			y:           "fCLRLet9OAysx2G4wo1KWOvjwNZleQrhnYgQz1m8tY89km2JttwutYKo_HL15aeK",
+		},
+		{
+			description: "verify 'x' and 'y' are correctly generated and padded for P-521",
+			certPath:    testPath + "/ec-p521.tls",
+			x:           "AP5HbmE1QrBeWw4c7WqjC2yE4XG1MXWFARHSqkIXES8BLF42LsQ2_ulqlJr2g-DSCpHT-0Ye4itQfGzEh9wEDxG1",
+			y:           "AGFRtxuuR2LnZcvV8cDref76M2BlmXzONn6F-Ig8h2876sBO3NVrzKByTqRc9IXrcEsU-MFtzP5-HSLi5OI_WEbp",
+		},
+		{
+			description: "verify 'x' is the raw public key and 'y' is not generated for Ed25519",
+			certPath:    testPath + "/ed25519.tls",
+			x:           "AzixnnjfnKU9Ks54h4_vd8PvhpMnAQfIR8sCjMXZ36M",
+			y:           "",
+		},
+	}
+
+	for _, test := range tests {
+		cert := loadCertificate(t, test.certPath)
+
+		// when
+		x, _ := jwks.X(cert)
+		y, _ := jwks.Y(cert)
+
+		// then
+		assert.Equalf(t, test.x, x, test.description)
+		assert.Equalf(t, test.y, y, test.description)
+	}
+}
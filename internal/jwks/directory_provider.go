@@ -0,0 +1,348 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"issuer-service-go/internal/config"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	directorySchedulerName = "JWKS Directory Provider - Scheduler"
+	directoryWatcherName   = "JWKS Directory Provider - Watcher"
+
+	certFileExtension = ".crt"
+	kidFileExtension  = ".kid"
+	algFileExtension  = ".alg"
+
+	directorySlotActive   = "active"
+	directorySlotInactive = "inactive"
+)
+
+// DirectoryProvider publishes every certificate found in the mounted directory.
+//
+// A certificate is identified by a '<name>.crt' file with a matching '<name>.kid' file and an optional
+// '<name>.alg' file. The active certificate is designated by CERT_ACTIVE_KID or by the content of the
+// marker file, both containing either the key ID or the base name of the certificate.
+// If neither is set and the directory contains exactly one certificate, it is the active one.
+//
+// The published keys are served from an immutable snapshot like the FileProvider does.
+type DirectoryProvider struct {
+	config *config.JwksFileConfig
+
+	snapshots *snapshotPublisher
+
+	// keys contains all certificates of the directory with the active one first
+	keys         []*Jwk
+	activeJwk    *Jwk
+	lastModified time.Time
+	lastReload   time.Time
+
+	expiryMonitor *expiryMonitor
+
+	cacheMutex *sync.Mutex
+	// reloadMutex serializes the reloads of the scheduler or watcher and the ones triggered on demand
+	reloadMutex *sync.Mutex
+
+	isSchedulerRunning bool
+	isWatcherRunning   bool
+}
+
+// NewDirectoryProvider loads the certificates of the mounted directory and keeps them up to date in the background
+// until ctx is done.
+func NewDirectoryProvider(ctx context.Context, jwksConfig *config.JwksFileConfig) (*DirectoryProvider, error) {
+	if jwksConfig.MountedPath == "" {
+		return nil, errors.New("failed to initialize DirectoryProvider: CERT_MOUNT_PATH must be set")
+	}
+
+	dp := &DirectoryProvider{
+		config:        jwksConfig,
+		snapshots:     &snapshotPublisher{excludeExpired: jwksConfig.ExcludeExpired},
+		expiryMonitor: newExpiryMonitor(jwksConfig.ExpiryWarnings),
+		cacheMutex:    &sync.Mutex{},
+		reloadMutex:   &sync.Mutex{},
+	}
+
+	log.Info().Msgf("initializing JWKS cache from directory %s...", jwksConfig.MountedPath)
+	if err := dp.updateCerts(); err != nil {
+		return nil, fmt.Errorf("failed to initialize DirectoryProvider: %w", err)
+	}
+	log.Info().Msgf("JWKS cache is initialized")
+
+	dp.startScheduler(ctx)
+	startExpiryCheck(ctx, dp.expiryMonitor, dp.cachedJwks)
+	return dp, nil
+}
+
+// GetJwks returns the JWKs of all certificates with the active one first, which are the same for every realm.
+// Expired JWKs are left out if CERT_EXCLUDE_EXPIRED is set. The returned slice is shared and must not be modified.
+func (dp *DirectoryProvider) GetJwks(_ string) []*Jwk {
+	return dp.currentSnapshot().Keys
+}
+
+// GetDefaultRealm returns the public key of the active JWK, or nil if there is none or it is expired.
+func (dp *DirectoryProvider) GetDefaultRealm(realm string) *DefaultRealm {
+	activeJwk := dp.currentSnapshot().activeJwk
+	if activeJwk == nil {
+		log.Warn().Msg("no active JWK available in cache for default realm")
+		return nil
+	}
+	if activeJwk.IsExpiredAt(time.Now()) {
+		log.Error().Msgf("active JWK with kid %s expired at %s", activeJwk.Kid, activeJwk.NotAfter)
+		return nil
+	}
+
+	return &DefaultRealm{
+		Realm:     realm,
+		PublicKey: activeJwk.PublicKey,
+	}
+}
+
+func (dp *DirectoryProvider) cachedJwks() []*Jwk {
+	dp.cacheMutex.Lock()
+	defer dp.cacheMutex.Unlock()
+
+	return slices.Clone(dp.keys)
+}
+
+// LoadedJwks returns the JWKs of all certificates with the active one first, including expired ones.
+func (dp *DirectoryProvider) LoadedJwks(_ string) []*Jwk {
+	return dp.cachedJwks()
+}
+
+func (dp *DirectoryProvider) LastModified(_ string) time.Time {
+	return dp.currentSnapshot().LastModified
+}
+
+// HasNextKey returns false, because the directory does not distinguish upcoming from previous certificates.
+func (dp *DirectoryProvider) HasNextKey(_ string) bool {
+	return false
+}
+
+// Snapshot returns the snapshot of the published keys, which is the same for every realm.
+func (dp *DirectoryProvider) Snapshot(_ string) *Snapshot {
+	return dp.currentSnapshot()
+}
+
+// currentSnapshot returns the snapshot of the published keys. It is only rebuilt if the certificates changed
+// or if a key expired that has to be excluded.
+func (dp *DirectoryProvider) currentSnapshot() *Snapshot {
+	return dp.snapshots.current(dp.cacheMutex, dp.publishSnapshot)
+}
+
+// publishSnapshot replaces the snapshot with the cached JWKs. It must be called while holding cacheMutex.
+func (dp *DirectoryProvider) publishSnapshot(now time.Time) error {
+	return dp.snapshots.publish(dp.keys, dp.activeJwk, dp.lastModified, false, now)
+}
+
+// LastReload returns the time of the last successful scan of the directory. The interval is 0 if the directory is
+// watched or never scanned again.
+func (dp *DirectoryProvider) LastReload(_ string) (time.Time, time.Duration) {
+	dp.cacheMutex.Lock()
+	defer dp.cacheMutex.Unlock()
+
+	if !dp.isSchedulerRunning {
+		return dp.lastReload, 0
+	}
+	return dp.lastReload, time.Duration(dp.config.UpdateInterval) * time.Second
+}
+
+// Reload scans the directory synchronously. The directory does not have slots, so no slot status is returned.
+func (dp *DirectoryProvider) Reload(_ string) ([]SlotStatus, error) {
+	return nil, dp.updateCerts()
+}
+
+func (dp *DirectoryProvider) IsSchedulerRunning() bool {
+	return dp.isSchedulerRunning
+}
+
+func (dp *DirectoryProvider) IsWatcherRunning() bool {
+	return dp.isWatcherRunning
+}
+
+func (dp *DirectoryProvider) startScheduler(ctx context.Context) {
+	if dp.config.ReloadMode == config.ReloadModeWatch {
+		dirs := []string{dp.config.MountedPath}
+		err := runWatcher(ctx, directoryWatcherName, dirs, dp.config.WatchDebounce, dp.executeTask)
+		if err == nil {
+			dp.isWatcherRunning = true
+			return
+		}
+		log.Warn().Msgf("%s is unavailable, falling back to polling: %v", directoryWatcherName, err)
+	}
+
+	dp.isSchedulerRunning = runScheduler(ctx, directorySchedulerName, dp.config.UpdateInterval, dp.executeTask)
+}
+
+func (dp *DirectoryProvider) executeTask() {
+	log.Debug().Msg("updating the certificates from mounted directory...")
+	if err := dp.updateCerts(); err != nil {
+		log.Error().Msgf("failed to update certificates: %v", err)
+		return
+	}
+	log.Debug().Msg("certificates were updated successfully")
+}
+
+// updateCerts scans the directory and records the result of the reload.
+func (dp *DirectoryProvider) updateCerts() error {
+	dp.reloadMutex.Lock()
+	defer dp.reloadMutex.Unlock()
+
+	err := dp.loadCerts()
+
+	keys := dp.cachedJwks()
+	keysPerSlot := map[string]int{directorySlotActive: 0, directorySlotInactive: len(keys)}
+	if len(keys) > 0 {
+		keysPerSlot = map[string]int{directorySlotActive: 1, directorySlotInactive: len(keys) - 1}
+	}
+	recordReload(config.ProviderDirectory, dp.config.Realm, keysPerSlot, keys, err)
+	dp.expiryMonitor.check(keys, time.Now())
+	return err
+}
+
+// loadCerts loads every certificate of the directory into the cache. If any certificate cannot be loaded or the
+// active one is not found, the cache is left unchanged.
+func (dp *DirectoryProvider) loadCerts() error {
+	names, err := scanCertNames(dp.config.MountedPath)
+	if err != nil {
+		return err
+	}
+
+	activeName, err := dp.readActiveName()
+	if err != nil {
+		return err
+	}
+	if activeName == "" && len(names) == 1 {
+		activeName = names[0]
+	}
+
+	var activeJwk *Jwk
+	keys := make([]*Jwk, 0, len(names))
+	for _, name := range names {
+		jwk, jwkErr := dp.generateCertInfo(name)
+		if jwkErr != nil {
+			return fmt.Errorf("failed to load certificate %s: %w", name, jwkErr)
+		}
+
+		if slices.ContainsFunc(keys, func(key *Jwk) bool { return key.Kid == jwk.Kid }) {
+			log.Debug().Msgf("JWK with kid %s already exists in cache", jwk.Kid)
+			continue
+		}
+
+		if activeJwk == nil && (name == activeName || jwk.Kid == activeName) {
+			jwk.Slot = directorySlotActive
+			activeJwk = jwk
+			keys = slices.Insert(keys, 0, jwk)
+			continue
+		}
+		jwk.Slot = directorySlotInactive
+		keys = append(keys, jwk)
+	}
+
+	if activeJwk == nil {
+		return fmt.Errorf("active certificate '%s' not found in %s", activeName, dp.config.MountedPath)
+	}
+
+	dp.cacheMutex.Lock()
+	defer dp.cacheMutex.Unlock()
+
+	if !keysEqual(dp.keys, keys) {
+		dp.lastModified = time.Now()
+	}
+	dp.keys = keys
+	dp.activeJwk = activeJwk
+	if err = dp.publishSnapshot(time.Now()); err != nil {
+		return err
+	}
+	dp.lastReload = time.Now()
+	return nil
+}
+
+// readActiveName returns the key ID or base name of the active certificate, or an empty string if it is
+// neither configured nor designated by the marker file.
+func (dp *DirectoryProvider) readActiveName() (string, error) {
+	if dp.config.ActiveKid != "" {
+		return dp.config.ActiveKid, nil
+	}
+
+	markerByteArray, err := os.ReadFile(path.Join(dp.config.MountedPath, dp.config.ActiveMarkerFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(markerByteArray)), nil
+}
+
+func (dp *DirectoryProvider) generateCertInfo(name string) (*Jwk, error) {
+	basePath := path.Join(dp.config.MountedPath, name)
+
+	certByteArray, err := os.ReadFile(basePath + certFileExtension)
+	if err != nil {
+		return nil, err
+	}
+
+	kidByteArray, err := os.ReadFile(basePath + kidFileExtension)
+	if err != nil {
+		return nil, err
+	}
+
+	alg, err := readAlg(basePath+algFileExtension, dp.config.Alg)
+	if err != nil {
+		return nil, err
+	}
+
+	jwk, err := newJwk(certByteArray, kidByteArray, alg)
+	if err != nil {
+		return nil, err
+	}
+	jwk.Source = basePath + certFileExtension
+	return jwk, nil
+}
+
+// scanCertNames returns the sorted base names of all certificate files in the directory that have a
+// matching key ID file. Hidden entries (e.g. the '..data' directory of Kubernetes volumes) are ignored.
+func scanCertNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if strings.HasPrefix(fileName, ".") || path.Ext(fileName) != certFileExtension {
+			continue
+		}
+
+		// entries of mounted volumes are usually symlinks, so the target has to be checked
+		info, statErr := os.Stat(path.Join(dir, fileName))
+		if statErr != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		name := strings.TrimSuffix(fileName, certFileExtension)
+		if _, statErr = os.Stat(path.Join(dir, name+kidFileExtension)); statErr != nil {
+			log.Debug().Msgf("ignoring certificate %s without key ID file", fileName)
+			continue
+		}
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	return names, nil
+}
@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks_test
+
+import (
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	directoryTestPath = "./directory_provider_testdata"
+
+	kidCurrent   = "F7959F8A-EC16-44BC-9F77-2A6F9580BDB4"
+	kidNext      = "271E7534-C67B-444C-9509-F9A45398EE09"
+	kidPrevious1 = "5A9C11C2-A370-473D-AB2B-4B8BC247724C"
+	kidPrevious2 = "C3B1E4A2-5D0F-4E7B-9A61-7F2D8C4B0E19"
+)
+
+func TestNewDirectoryProvider(t *testing.T) {
+	tests := []struct {
+		name              string
+		config            *config.JwksFileConfig
+		err               bool
+		expectedKids      []string
+		expectedActiveKid string
+	}{
+		{
+			name: "all certificates are published with the active one from the marker file first",
+			config: &config.JwksFileConfig{
+				MountedPath:      directoryTestPath,
+				ActiveMarkerFile: "active",
+			},
+			expectedKids:      []string{kidCurrent, kidNext, kidPrevious1, kidPrevious2},
+			expectedActiveKid: kidCurrent,
+		},
+		{
+			name: "configured active key ID takes precedence over the marker file",
+			config: &config.JwksFileConfig{
+				MountedPath:      directoryTestPath,
+				ActiveMarkerFile: "active",
+				ActiveKid:        kidNext,
+			},
+			expectedKids:      []string{kidNext, kidCurrent, kidPrevious1, kidPrevious2},
+			expectedActiveKid: kidNext,
+		},
+		{
+			name: "error if the active certificate is not designated",
+			config: &config.JwksFileConfig{
+				MountedPath:      directoryTestPath,
+				ActiveMarkerFile: "missing-marker",
+			},
+			err: true,
+		},
+		{
+			name: "error if the designated active certificate does not exist",
+			config: &config.JwksFileConfig{
+				MountedPath:      directoryTestPath,
+				ActiveMarkerFile: "active",
+				ActiveKid:        "unknown",
+			},
+			err: true,
+		},
+		{
+			name: "error with invalid MountedPath",
+			config: &config.JwksFileConfig{
+				MountedPath:      "./directory_provider_invalid",
+				ActiveMarkerFile: "active",
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwksProvider, err := jwks.NewDirectoryProvider(t.Context(), tt.config)
+			assert.Equalf(t, tt.err, err != nil, "expected error: %v, got: %v", tt.err, err)
+			if err != nil {
+				return
+			}
+
+			kids := make([]string, 0, len(tt.expectedKids))
+			for _, jwk := range jwksProvider.GetJwks("default") {
+				kids = append(kids, jwk.Kid)
+			}
+			assert.Equal(t, tt.expectedKids, kids)
+
+			defaultRealm := jwksProvider.GetDefaultRealm("default")
+			if assert.NotNil(t, defaultRealm) {
+				assert.Equal(t, jwksProvider.GetJwks("default")[0].PublicKey, defaultRealm.PublicKey)
+			}
+			assert.Equal(t, tt.expectedActiveKid, jwksProvider.GetJwks("default")[0].Kid)
+		})
+	}
+}
+
+func TestDirectoryProviderWithSingleCertificate(t *testing.T) {
+	dir := t.TempDir()
+	copyTestFile(t, path.Join(directoryTestPath, "current.crt"), path.Join(dir, "tls.crt"))
+	copyTestFile(t, path.Join(directoryTestPath, "current.kid"), path.Join(dir, "tls.kid"))
+
+	jwksProvider, err := jwks.NewDirectoryProvider(t.Context(), &config.JwksFileConfig{
+		MountedPath:      dir,
+		ActiveMarkerFile: "active",
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWKS directory provider: %v", err)
+	}
+
+	assert.Len(t, jwksProvider.GetJwks("default"), 1)
+	assert.NotNil(t, jwksProvider.GetDefaultRealm("default"))
+}
+
+func TestDirectoryProviderScheduler(t *testing.T) {
+	dir := t.TempDir()
+	copyTestFile(t, path.Join(directoryTestPath, "current.crt"), path.Join(dir, "current.crt"))
+	copyTestFile(t, path.Join(directoryTestPath, "current.kid"), path.Join(dir, "current.kid"))
+	copyTestFile(t, path.Join(directoryTestPath, "active"), path.Join(dir, "active"))
+
+	jwksProvider, err := jwks.NewDirectoryProvider(t.Context(), &config.JwksFileConfig{
+		UpdateInterval:   1,
+		MountedPath:      dir,
+		ActiveMarkerFile: "active",
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWKS directory provider: %v", err)
+	}
+	assert.True(t, jwksProvider.IsSchedulerRunning())
+	assert.Len(t, jwksProvider.GetJwks("default"), 1)
+
+	copyTestFile(t, path.Join(directoryTestPath, "previous-1.crt"), path.Join(dir, "previous-1.crt"))
+	copyTestFile(t, path.Join(directoryTestPath, "previous-1.kid"), path.Join(dir, "previous-1.kid"))
+
+	assert.Eventually(t, func() bool {
+		return len(jwksProvider.GetJwks("default")) == 2
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
+func TestDirectoryProviderWatchMode(t *testing.T) {
+	dir := t.TempDir()
+	copyTestFile(t, path.Join(directoryTestPath, "current.crt"), path.Join(dir, "current.crt"))
+	copyTestFile(t, path.Join(directoryTestPath, "current.kid"), path.Join(dir, "current.kid"))
+	copyTestFile(t, path.Join(directoryTestPath, "active"), path.Join(dir, "active"))
+
+	jwksProvider, err := jwks.NewDirectoryProvider(t.Context(), &config.JwksFileConfig{
+		UpdateInterval:   10,
+		MountedPath:      dir,
+		ActiveMarkerFile: "active",
+		ReloadMode:       config.ReloadModeWatch,
+		WatchDebounce:    50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWKS directory provider: %v", err)
+	}
+	assert.True(t, jwksProvider.IsWatcherRunning())
+	assert.False(t, jwksProvider.IsSchedulerRunning())
+
+	copyTestFile(t, path.Join(directoryTestPath, "next.crt"), path.Join(dir, "next.crt"))
+	copyTestFile(t, path.Join(directoryTestPath, "next.kid"), path.Join(dir, "next.kid"))
+	if err = os.WriteFile(path.Join(dir, "active"), []byte("next"), 0o600); err != nil {
+		t.Fatalf("failed to write marker file: %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		keys := jwksProvider.GetJwks("default")
+		return len(keys) == 2 && keys[0].Kid == kidNext
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDirectoryProviderTrimsKid(t *testing.T) {
+	dir := t.TempDir()
+	copyTestFile(t, path.Join(directoryTestPath, "current.crt"), path.Join(dir, "tls.crt"))
+	if err := os.WriteFile(path.Join(dir, "tls.kid"), []byte(kidCurrent+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write key ID file: %v", err)
+	}
+
+	jwksProvider, err := jwks.NewDirectoryProvider(t.Context(), &config.JwksFileConfig{
+		MountedPath: dir,
+		ActiveKid:   kidCurrent,
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWKS directory provider: %v", err)
+	}
+
+	assert.Equal(t, []string{kidCurrent}, kidsOf(jwksProvider.GetJwks("default")))
+}
+
+func copyTestFile(t *testing.T, src string, dst string) {
+	t.Helper()
+
+	content, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatalf("failed to read file %s: %v", src, err)
+	}
+	if err = os.WriteFile(dst, content, 0o600); err != nil {
+		t.Fatalf("failed to write file %s: %v", dst, err)
+	}
+}
+
+func TestDirectoryProviderReload(t *testing.T) {
+	dir := t.TempDir()
+	copyTestFile(t, path.Join(directoryTestPath, "current.crt"), path.Join(dir, "current.crt"))
+	copyTestFile(t, path.Join(directoryTestPath, "current.kid"), path.Join(dir, "current.kid"))
+	copyTestFile(t, path.Join(directoryTestPath, "active"), path.Join(dir, "active"))
+
+	jwksProvider, err := jwks.NewDirectoryProvider(t.Context(), &config.JwksFileConfig{
+		MountedPath:      dir,
+		ActiveMarkerFile: "active",
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWKS directory provider: %v", err)
+	}
+	assert.False(t, jwksProvider.IsSchedulerRunning())
+
+	copyTestFile(t, path.Join(directoryTestPath, "next.crt"), path.Join(dir, "next.crt"))
+	copyTestFile(t, path.Join(directoryTestPath, "next.kid"), path.Join(dir, "next.kid"))
+
+	_, err = jwksProvider.Reload("default")
+	assert.NoError(t, err)
+	assert.Len(t, jwksProvider.GetJwks("default"), 2)
+
+	// a failed reload keeps the published keys
+	if err = os.WriteFile(path.Join(dir, "next.crt"), []byte("-----BEGIN CERT"), 0o600); err != nil {
+		t.Fatalf("failed to write certificate file: %v", err)
+	}
+
+	_, err = jwksProvider.Reload("default")
+	assert.Error(t, err)
+	assert.Len(t, jwksProvider.GetJwks("default"), 2)
+}
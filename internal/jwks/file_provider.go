@@ -5,11 +5,12 @@
 package jwks
 
 import (
-	"crypto/x509"
-	"encoding/pem"
+	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"issuer-service-go/internal/config"
+	"maps"
 	"os"
 	"sync"
 	"time"
@@ -19,86 +20,183 @@ import (
 
 const (
 	schedulerName = "JWKS File Provider - Scheduler"
+	watcherName   = "JWKS File Provider - Watcher"
 )
 
-type Jwk struct {
-	Kid       string   `json:"kid"`
-	Kty       string   `json:"kty"`
-	Alg       string   `json:"alg"`
-	Use       string   `json:"use"`
-	N         string   `json:"n"`
-	E         string   `json:"e"`
-	X5c       []string `json:"x5c"`
-	X5t       string   `json:"x5t"`
-	X5tS256   string   `json:"x5t#S256"`
-	PublicKey string   `json:"-"`
-}
-
-type DefaultRealm struct {
-	Realm     string `json:"realm"`
-	PublicKey string `json:"public_key"`
+// SlotStatus describes the result of the last attempt to load the certificate of a slot.
+type SlotStatus struct {
+	Slot        string    `json:"slot"`
+	Optional    bool      `json:"optional"`
+	Kid         string    `json:"kid,omitempty"`   // key ID of the JWK that is currently served for the slot
+	Loaded      bool      `json:"loaded"`          // whether the last attempt was successful
+	Retained    bool      `json:"retained"`        // whether the last successfully loaded JWK is served, because the last attempt failed
+	Error       string    `json:"error,omitempty"` // error of the last attempt
+	LastAttempt time.Time `json:"lastAttempt"`
+	LastSuccess time.Time `json:"lastSuccess"`
 }
 
+// FileProvider publishes the certificates of the next, active and previous slot from mounted files.
+//
+// The published keys are served from an immutable snapshot, which is replaced whenever the keys change. Reading
+// the keys therefore never blocks, even while the certificates are reloaded.
 type FileProvider struct {
 	config *config.JwksFileConfig
 
+	snapshots *snapshotPublisher
+
 	certsCacheMap map[config.Type]*Jwk
+	lastModified  time.Time
+	lastReload    time.Time
+
+	// slotJwks contains the last successfully loaded JWK of every slot, which is retained if loading fails
+	slotJwks   map[config.Type]*Jwk
+	slotStatus map[config.Type]SlotStatus
+
+	expiryMonitor *expiryMonitor
 
 	cacheMutex *sync.Mutex
+	// reloadMutex serializes the reloads of the scheduler or watcher and the ones triggered on demand
+	reloadMutex *sync.Mutex
 
 	isSchedulerRunning bool
+	isWatcherRunning   bool
 }
 
-func NewFileProvider(jwksConfig *config.JwksFileConfig) (*FileProvider, error) {
+// NewFileProvider loads the certificates of the mounted files and keeps them up to date in the background until
+// ctx is done.
+func NewFileProvider(ctx context.Context, jwksConfig *config.JwksFileConfig) (*FileProvider, error) {
+	if jwksConfig.MountedPath == "" {
+		return nil, errors.New("failed to initialize FileProvider: CERT_MOUNT_PATH must be set")
+	}
+
 	fp := &FileProvider{
 		config:        jwksConfig,
+		snapshots:     &snapshotPublisher{excludeExpired: jwksConfig.ExcludeExpired},
 		certsCacheMap: make(map[config.Type]*Jwk),
+		slotJwks:      make(map[config.Type]*Jwk),
+		slotStatus:    make(map[config.Type]SlotStatus),
+		expiryMonitor: newExpiryMonitor(jwksConfig.ExpiryWarnings),
 		cacheMutex:    &sync.Mutex{},
+		reloadMutex:   &sync.Mutex{},
 	}
-	if err := initialize(fp); err != nil {
+	if err := initialize(ctx, fp); err != nil {
 		return nil, fmt.Errorf("failed to initialize FileProvider: %w", err)
 	}
 	return fp, nil
 }
 
-func (fp *FileProvider) GetJwks() []*Jwk {
+// GetJwks returns the JWKs of the next, active and previous slot, which are the same for every realm. Expired
+// JWKs are left out if CERT_EXCLUDE_EXPIRED is set. The returned slice is shared and must not be modified.
+func (fp *FileProvider) GetJwks(_ string) []*Jwk {
+	return fp.currentSnapshot().Keys
+}
+
+// GetDefaultRealm returns the public key of the active JWK, or nil if there is none or it is expired.
+func (fp *FileProvider) GetDefaultRealm(realm string) *DefaultRealm {
+	activeJwk := fp.currentSnapshot().activeJwk
+	if activeJwk == nil {
+		log.Warn().Msg("no active JWK available in cache for default realm")
+		return nil
+	}
+	if activeJwk.IsExpiredAt(time.Now()) {
+		log.Error().Msgf("active JWK with kid %s expired at %s", activeJwk.Kid, activeJwk.NotAfter)
+		return nil
+	}
+
+	defaultRealm := &DefaultRealm{
+		Realm:     realm,
+		PublicKey: activeJwk.PublicKey,
+	}
+
+	return defaultRealm
+}
+
+func (fp *FileProvider) cachedJwks() []*Jwk {
+	fp.cacheMutex.Lock()
+	defer fp.cacheMutex.Unlock()
+
+	return orderedJwks(fp.certsCacheMap)
+}
+
+// LoadedJwks returns the JWKs of the next, active and previous slot, including expired ones.
+func (fp *FileProvider) LoadedJwks(_ string) []*Jwk {
+	return fp.cachedJwks()
+}
+
+func (fp *FileProvider) LastModified(_ string) time.Time {
+	return fp.currentSnapshot().LastModified
+}
+
+func (fp *FileProvider) HasNextKey(_ string) bool {
+	return fp.currentSnapshot().HasNextKey
+}
+
+// Snapshot returns the snapshot of the published keys, which is the same for every realm.
+func (fp *FileProvider) Snapshot(_ string) *Snapshot {
+	return fp.currentSnapshot()
+}
+
+// currentSnapshot returns the snapshot of the published keys. It is only rebuilt if the certificates changed
+// or if a key expired that has to be excluded.
+func (fp *FileProvider) currentSnapshot() *Snapshot {
+	return fp.snapshots.current(fp.cacheMutex, fp.publishSnapshot)
+}
+
+// publishSnapshot replaces the snapshot with the cached JWKs. It must be called while holding cacheMutex.
+func (fp *FileProvider) publishSnapshot(now time.Time) error {
+	_, hasNextKey := fp.certsCacheMap[config.Next]
+	return fp.snapshots.publish(
+		orderedJwks(fp.certsCacheMap), fp.certsCacheMap[config.Active], fp.lastModified, hasNextKey, now)
+}
+
+// GetSlotStatus returns the load status of the next, active and previous slot.
+func (fp *FileProvider) GetSlotStatus() []SlotStatus {
 	fp.cacheMutex.Lock()
 	defer fp.cacheMutex.Unlock()
 
 	keyOrder := []config.Type{config.Next, config.Active, config.Previous}
 
-	values := make([]*Jwk, 0, len(keyOrder))
+	values := make([]SlotStatus, 0, len(keyOrder))
 	for _, key := range keyOrder {
-		if jwk, exists := fp.certsCacheMap[key]; exists {
-			values = append(values, jwk)
-		}
+		values = append(values, fp.slotStatus[key])
 	}
 	return values
 }
 
-func (fp *FileProvider) GetDefaultRealm(realm string) *DefaultRealm {
+// SlotStatus returns the load status of the next, active and previous slot, which are the same for every realm.
+func (fp *FileProvider) SlotStatus(_ string) []SlotStatus {
+	return fp.GetSlotStatus()
+}
+
+// Reload loads the certificates of all slots synchronously and returns the load status of the next, active and
+// previous slot.
+func (fp *FileProvider) Reload(_ string) ([]SlotStatus, error) {
+	err := updateCerts(fp)
+	return fp.GetSlotStatus(), err
+}
+
+// LastReload returns the time of the last reload in which the active slot was loaded. A failing next or previous
+// slot does not hold it back, because the active key is still served. The interval is 0 if the certificates are
+// watched or never reloaded.
+func (fp *FileProvider) LastReload(_ string) (time.Time, time.Duration) {
 	fp.cacheMutex.Lock()
 	defer fp.cacheMutex.Unlock()
 
-	activeJwk, exists := fp.certsCacheMap[config.Active]
-	if !exists {
-		log.Warn().Msg("no active JWK available in cache for default realm")
-		return nil
-	}
-
-	defaultRealm := &DefaultRealm{
-		Realm:     realm,
-		PublicKey: activeJwk.PublicKey,
+	if !fp.isSchedulerRunning {
+		return fp.lastReload, 0
 	}
-
-	return defaultRealm
+	return fp.lastReload, time.Duration(fp.config.UpdateInterval) * time.Second
 }
 
 func (fp *FileProvider) IsSchedulerRunning() bool {
 	return fp.isSchedulerRunning
 }
 
-func initialize(fp *FileProvider) error {
+func (fp *FileProvider) IsWatcherRunning() bool {
+	return fp.isWatcherRunning
+}
+
+func initialize(ctx context.Context, fp *FileProvider) error {
 	log.Info().Msgf("initializing JWKS cache...")
 
 	if err := updateCerts(fp); err != nil {
@@ -106,35 +204,84 @@ func initialize(fp *FileProvider) error {
 	}
 
 	log.Info().Msgf("JWKS cache is initialized")
-	startScheduler(fp)
+	startScheduler(ctx, fp)
+	startExpiryCheck(ctx, fp.expiryMonitor, fp.cachedJwks)
 	return nil
 }
 
+// updateCerts loads the certificates of all slots and publishes them.
+//
+// If a slot fails to load, the last successfully loaded JWK of the slot is retained, so a transient error
+// (e.g. a half-written file) never empties the JWKS. An optional slot whose files do not exist is published
+// empty. The returned error contains the failures of all slots.
 func updateCerts(fp *FileProvider) error {
-	jwkNext, err := generateCertInfo(fp.config, config.Next)
-	if err != nil {
-		return err
-	}
-	jwkActive, err := generateCertInfo(fp.config, config.Active)
-	if err != nil {
-		return err
-	}
-	jwkPrev, err := generateCertInfo(fp.config, config.Previous)
-	if err != nil {
-		return err
+	fp.reloadMutex.Lock()
+	defer fp.reloadMutex.Unlock()
+
+	fp.cacheMutex.Lock()
+	slotJwks := maps.Clone(fp.slotJwks)
+	slotStatus := maps.Clone(fp.slotStatus)
+	fp.cacheMutex.Unlock()
+
+	var errs []error
+	for _, certType := range []config.Type{config.Active, config.Previous, config.Next} {
+		status := slotStatus[certType]
+		status.Slot = certType.String()
+		status.Optional = fp.config.IsOptional(certType)
+		status.LastAttempt = time.Now()
+
+		jwk, err := generateCertInfo(fp.config, certType)
+		switch {
+		case err == nil:
+			slotJwks[certType] = jwk
+			status.Loaded, status.Retained, status.Error = true, false, ""
+			status.LastSuccess = status.LastAttempt
+		case status.Optional && errors.Is(err, fs.ErrNotExist):
+			log.Debug().Msgf("optional %s certificate does not exist: %v", certType, err)
+			delete(slotJwks, certType)
+			status.Loaded, status.Retained, status.Error = false, false, ""
+		default:
+			_, status.Retained = slotJwks[certType]
+			status.Loaded, status.Error = false, err.Error()
+			if status.Retained {
+				log.Warn().Msgf("failed to load %s certificate, retaining last known good JWK: %v", certType, err)
+			}
+			errs = append(errs, fmt.Errorf("failed to load %s certificate: %w", certType, err))
+		}
+
+		status.Kid = ""
+		if retainedJwk, exists := slotJwks[certType]; exists {
+			status.Kid = retainedJwk.Kid
+		}
+		slotStatus[certType] = status
 	}
 
 	certsCacheMap := make(map[config.Type]*Jwk)
-
-	addJwkToCache(certsCacheMap, config.Active, jwkActive)
-	addJwkToCache(certsCacheMap, config.Previous, jwkPrev)
-	addJwkToCache(certsCacheMap, config.Next, jwkNext)
+	for _, certType := range []config.Type{config.Active, config.Previous, config.Next} {
+		if jwk, exists := slotJwks[certType]; exists {
+			addJwkToCache(certsCacheMap, certType, jwk)
+		}
+	}
 
 	fp.cacheMutex.Lock()
+	if !keysEqual(orderedJwks(fp.certsCacheMap), orderedJwks(certsCacheMap)) {
+		fp.lastModified = time.Now()
+	}
 	fp.certsCacheMap = certsCacheMap
+	fp.slotJwks = slotJwks
+	fp.slotStatus = slotStatus
+	if err := fp.publishSnapshot(time.Now()); err != nil {
+		errs = append(errs, err)
+	} else if slotStatus[config.Active].Loaded {
+		fp.lastReload = time.Now()
+	}
 	fp.cacheMutex.Unlock()
 
-	return nil
+	err := errors.Join(errs...)
+	keys := fp.cachedJwks()
+	recordReload(config.ProviderFile, fp.config.Realm, countSlots(certsCacheMap), keys, err)
+	fp.expiryMonitor.check(keys, time.Now())
+	return err
 }
 
 func addJwkToCache(certsCacheMap map[config.Type]*Jwk, certType config.Type, jwk *Jwk) {
@@ -166,65 +313,35 @@ func generateCertInfo(config *config.JwksFileConfig, certType config.Type) (*Jwk
 		return nil, err
 	}
 
-	block, _ := pem.Decode(certByteArray)
-	if block == nil {
-		return nil, errors.New("failed to decode certificate PEM")
-	}
-
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse certificate: %w", err)
-	}
-
-	exponent, err := E(cert)
-	if err != nil {
-		return nil, fmt.Errorf("unable to create JWK: %w", err)
-	}
-
-	modulus, err := N(cert)
+	alg, err := readAlg(config.GetAlgFile(certType), config.Alg)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create JWK: %w", err)
+		return nil, err
 	}
 
-	// Extract and format the public key
-	publicKeyString, err := PublicKey(cert)
+	jwk, err := newJwk(certByteArray, kidByteArray, alg)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read Public Key: %w", err)
-	}
-
-	jwk := Jwk{
-		Kid:       string(kidByteArray),
-		Kty:       "RSA",
-		Alg:       Alg(),
-		Use:       "sig",
-		E:         exponent,
-		N:         modulus,
-		X5c:       X5c(cert),
-		X5t:       X5t(cert),
-		X5tS256:   X5tS256(cert),
-		PublicKey: publicKeyString,
+		return nil, err
 	}
-
-	return &jwk, nil
+	jwk.Slot = certType.String()
+	jwk.Source = certFile
+	return jwk, nil
 }
 
-func startScheduler(fp *FileProvider) {
-	if fp.config.UpdateInterval == 0 {
-		log.Info().Msgf("%s is deactivated", schedulerName)
-		return
-	}
-
-	log.Info().Msgf("starting %s ...", schedulerName)
-	ticker := time.NewTicker(time.Duration(fp.config.UpdateInterval) * time.Second)
-
-	go func() {
-		for range ticker.C {
+func startScheduler(ctx context.Context, fp *FileProvider) {
+	if fp.config.ReloadMode == config.ReloadModeWatch {
+		err := runWatcher(ctx, watcherName, fp.config.GetWatchDirs(), fp.config.WatchDebounce, func() {
 			executeTask(fp)
+		})
+		if err == nil {
+			fp.isWatcherRunning = true
+			return
 		}
-	}()
+		log.Warn().Msgf("%s is unavailable, falling back to polling: %v", watcherName, err)
+	}
 
-	fp.isSchedulerRunning = true
-	log.Info().Msgf("%s started", schedulerName)
+	fp.isSchedulerRunning = runScheduler(ctx, schedulerName, fp.config.UpdateInterval, func() {
+		executeTask(fp)
+	})
 }
 
 func executeTask(fp *FileProvider) {
@@ -232,6 +349,7 @@ func executeTask(fp *FileProvider) {
 	err := updateCerts(fp)
 	if err != nil {
 		log.Error().Msgf("failed to update certificate: %v", err)
+		return
 	}
 	log.Debug().Msg("certificates were updated successfully")
 }
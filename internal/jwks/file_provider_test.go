@@ -7,6 +7,8 @@ package jwks_test
 import (
 	"issuer-service-go/internal/config"
 	"issuer-service-go/internal/jwks"
+	"os"
+	"path"
 	"testing"
 	"time"
 
@@ -135,7 +137,7 @@ func TestNewFileProvider(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := jwks.NewFileProvider(tt.config)
+			_, err := jwks.NewFileProvider(t.Context(), tt.config)
 			assert.Equalf(t, tt.err, err != nil, "expected error: %v, got: %v", tt.err, err)
 		})
 	}
@@ -182,11 +184,11 @@ func TestGetJwks(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			jwksProvider, _ := jwks.NewFileProvider(tt.config)
+			jwksProvider, _ := jwks.NewFileProvider(t.Context(), tt.config)
 			time.Sleep(3 * time.Second)
 			assert.Truef(t, jwksProvider.IsSchedulerRunning(), "expected scheduler to be running, but it is not")
 
-			jwKeySet := jwksProvider.GetJwks()
+			jwKeySet := jwksProvider.GetJwks("default")
 			assert.Lenf(
 				t,
 				jwKeySet,
@@ -198,3 +200,414 @@ func TestGetJwks(t *testing.T) {
 		})
 	}
 }
+
+func TestGetJwksWithEcCertificate(t *testing.T) {
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./file_provider_testdata",
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "ec-tls.crt",
+		KidFileNameActive:  "ec-tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+
+	jwKeySet := jwksProvider.GetJwks("default")
+	if !assert.Len(t, jwKeySet, 3) {
+		return
+	}
+
+	ecJwk := jwKeySet[1]
+	assert.Equal(t, "C3B1E4A2-5D0F-4E7B-9A61-7F2D8C4B0E19", ecJwk.Kid)
+	assert.Equal(t, "EC", ecJwk.Kty)
+	assert.Equal(t, "ES256", ecJwk.Alg)
+	assert.Equal(t, "sig", ecJwk.Use)
+	assert.Equal(t, "P-256", ecJwk.Crv)
+	assert.NotEmpty(t, ecJwk.X)
+	assert.NotEmpty(t, ecJwk.Y)
+	assert.Empty(t, ecJwk.N)
+	assert.Empty(t, ecJwk.E)
+	assert.Len(t, ecJwk.X5c, 1)
+	assert.NotEmpty(t, ecJwk.X5t)
+	assert.NotEmpty(t, ecJwk.X5tS256)
+
+	rsaJwk := jwKeySet[0]
+	assert.Equal(t, "RSA", rsaJwk.Kty)
+	assert.Empty(t, rsaJwk.Crv)
+}
+
+func TestGetJwksWithEd25519Certificate(t *testing.T) {
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./file_provider_testdata",
+		CertFileNameNext:   "ed25519-tls.crt",
+		KidFileNameNext:    "ed25519-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+
+	jwKeySet := jwksProvider.GetJwks("default")
+	if !assert.Len(t, jwKeySet, 3) {
+		return
+	}
+
+	okpJwk := jwKeySet[0]
+	assert.Equal(t, "9E0A6F3B-2C71-4D58-B8E4-1A5C7D3F6B20", okpJwk.Kid)
+	assert.Equal(t, "OKP", okpJwk.Kty)
+	assert.Equal(t, "EdDSA", okpJwk.Alg)
+	assert.Equal(t, "Ed25519", okpJwk.Crv)
+	assert.Equal(t, "AzixnnjfnKU9Ks54h4_vd8PvhpMnAQfIR8sCjMXZ36M", okpJwk.X)
+	assert.Empty(t, okpJwk.Y)
+	assert.Empty(t, okpJwk.N)
+	assert.Empty(t, okpJwk.E)
+	assert.Len(t, okpJwk.X5c, 1)
+}
+
+func TestGetJwksWithConfiguredAlg(t *testing.T) {
+	tests := []struct {
+		name         string
+		alg          string
+		expectedAlgs []string
+		err          bool
+	}{
+		{
+			name:         "algorithm is read from the '.alg' file next to the key ID file",
+			alg:          "",
+			expectedAlgs: []string{"RS256", "PS256", "RS256"},
+		},
+		{
+			name:         "configured algorithm is used if no '.alg' file exists",
+			alg:          "RS384",
+			expectedAlgs: []string{"RS384", "PS256", "RS384"},
+		},
+		{
+			name: "error if the configured algorithm does not match the key",
+			alg:  "ES256",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwksConfig := &config.JwksFileConfig{
+				UpdateInterval:     0,
+				MountedPath:        "./file_provider_testdata",
+				CertFileNameNext:   "next-tls.crt",
+				KidFileNameNext:    "next-tls.kid",
+				CertFileNameActive: "ps-tls.crt",
+				KidFileNameActive:  "ps-tls.kid",
+				CertFileNamePrev:   "prev-tls.crt",
+				KidFileNamePrev:    "prev-tls.kid",
+				Alg:                tt.alg,
+			}
+
+			jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+			assert.Equalf(t, tt.err, err != nil, "expected error: %v, got: %v", tt.err, err)
+			if err != nil {
+				return
+			}
+
+			algs := make([]string, 0, len(tt.expectedAlgs))
+			for _, jwk := range jwksProvider.GetJwks("default") {
+				algs = append(algs, jwk.Alg)
+			}
+			assert.Equal(t, tt.expectedAlgs, algs)
+			assert.Equal(t, []string{"PS256", tt.expectedAlgs[0]}, jwks.SigningAlgs(jwksProvider.GetJwks("default")))
+		})
+	}
+}
+
+// writeKubernetesVolume writes the files in the way Kubernetes updates mounted volumes: the files are written
+// to a new timestamped directory and the '..data' symlink is atomically swapped to point to it.
+func writeKubernetesVolume(t *testing.T, dir string, version string, files map[string]string) {
+	t.Helper()
+
+	dataDir := "..data_" + version
+	if err := os.Mkdir(path.Join(dir, dataDir), 0o700); err != nil {
+		t.Fatalf("failed to create data directory: %v", err)
+	}
+	for name, src := range files {
+		copyTestFile(t, src, path.Join(dir, dataDir, name))
+	}
+
+	if err := os.Symlink(dataDir, path.Join(dir, "..data_tmp")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	if err := os.Rename(path.Join(dir, "..data_tmp"), path.Join(dir, "..data")); err != nil {
+		t.Fatalf("failed to swap symlink: %v", err)
+	}
+
+	for name := range files {
+		link := path.Join(dir, name)
+		if _, err := os.Lstat(link); err == nil {
+			continue
+		}
+		if err := os.Symlink(path.Join("..data", name), link); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+	}
+}
+
+func TestFileProviderWatchMode(t *testing.T) {
+	testdata := "./file_provider_testdata"
+	dir := t.TempDir()
+	writeKubernetesVolume(t, dir, "1", map[string]string{
+		"next-tls.crt": path.Join(testdata, "next-tls.crt"),
+		"next-tls.kid": path.Join(testdata, "next-tls.kid"),
+		"tls.crt":      path.Join(testdata, "tls.crt"),
+		"tls.kid":      path.Join(testdata, "tls.kid"),
+		"prev-tls.crt": path.Join(testdata, "prev-tls.crt"),
+		"prev-tls.kid": path.Join(testdata, "prev-tls.kid"),
+	})
+
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), &config.JwksFileConfig{
+		UpdateInterval:     10,
+		MountedPath:        dir,
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+		ReloadMode:         config.ReloadModeWatch,
+		WatchDebounce:      50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	assert.True(t, jwksProvider.IsWatcherRunning())
+	assert.False(t, jwksProvider.IsSchedulerRunning())
+	assert.Equal(t, kidCurrent, jwksProvider.GetJwks("default")[1].Kid)
+
+	// rotate: next becomes active, active becomes previous and a new next key is added
+	writeKubernetesVolume(t, dir, "2", map[string]string{
+		"next-tls.crt": path.Join(testdata, "ec-tls.crt"),
+		"next-tls.kid": path.Join(testdata, "ec-tls.kid"),
+		"tls.crt":      path.Join(testdata, "next-tls.crt"),
+		"tls.kid":      path.Join(testdata, "next-tls.kid"),
+		"prev-tls.crt": path.Join(testdata, "tls.crt"),
+		"prev-tls.kid": path.Join(testdata, "tls.kid"),
+	})
+
+	assert.Eventually(t, func() bool {
+		keys := jwksProvider.GetJwks("default")
+		return len(keys) == 3 && keys[0].Kid == kidPrevious2 && keys[1].Kid == kidNext && keys[2].Kid == kidCurrent
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func copySlotFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		copyTestFile(t, path.Join("./file_provider_testdata", name+".crt"), path.Join(dir, name+".crt"))
+		copyTestFile(t, path.Join("./file_provider_testdata", name+".kid"), path.Join(dir, name+".kid"))
+	}
+}
+
+func newSlotTestConfig(dir string) *config.JwksFileConfig {
+	return &config.JwksFileConfig{
+		UpdateInterval:     1,
+		MountedPath:        dir,
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+}
+
+func TestFileProviderOptionalSlots(t *testing.T) {
+	tests := []struct {
+		name         string
+		nextOptional bool
+		prevOptional bool
+		err          bool
+		expectedKids []string
+	}{
+		{
+			name:         "missing next and previous certificates are skipped if optional",
+			nextOptional: true,
+			prevOptional: true,
+			expectedKids: []string{kidCurrent},
+		},
+		{
+			name:         "error if a missing certificate is not optional",
+			nextOptional: true,
+			prevOptional: false,
+			err:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			copySlotFiles(t, dir, "tls")
+
+			jwksConfig := newSlotTestConfig(dir)
+			jwksConfig.UpdateInterval = 0
+			jwksConfig.NextOptional = tt.nextOptional
+			jwksConfig.PrevOptional = tt.prevOptional
+
+			jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+			assert.Equalf(t, tt.err, err != nil, "expected error: %v, got: %v", tt.err, err)
+			if err != nil {
+				return
+			}
+
+			kids := make([]string, 0, len(tt.expectedKids))
+			for _, jwk := range jwksProvider.GetJwks("default") {
+				kids = append(kids, jwk.Kid)
+			}
+			assert.Equal(t, tt.expectedKids, kids)
+
+			slotStatus := jwksProvider.GetSlotStatus()
+			assert.Equal(t, "next", slotStatus[0].Slot)
+			assert.True(t, slotStatus[0].Optional)
+			assert.False(t, slotStatus[0].Loaded)
+			assert.Empty(t, slotStatus[0].Error)
+			assert.Equal(t, "active", slotStatus[1].Slot)
+			assert.True(t, slotStatus[1].Loaded)
+			assert.Equal(t, kidCurrent, slotStatus[1].Kid)
+		})
+	}
+}
+
+func TestFileProviderRetainsLastKnownGoodJwk(t *testing.T) {
+	dir := t.TempDir()
+	copySlotFiles(t, dir, "next-tls", "tls", "prev-tls")
+
+	jwksConfig := newSlotTestConfig(dir)
+	jwksConfig.PrevOptional = true
+
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	assert.Len(t, jwksProvider.GetJwks("default"), 3)
+	lastReload, interval := jwksProvider.LastReload("default")
+	assert.False(t, lastReload.IsZero())
+	assert.Equal(t, time.Duration(jwksConfig.UpdateInterval)*time.Second, interval)
+
+	// a half-written active certificate does not remove the active key
+	if err = os.WriteFile(path.Join(dir, "tls.crt"), []byte("-----BEGIN CERT"), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		return jwksProvider.GetSlotStatus()[1].Retained
+	}, 5*time.Second, 50*time.Millisecond)
+
+	activeStatus := jwksProvider.GetSlotStatus()[1]
+	assert.False(t, activeStatus.Loaded)
+	assert.NotEmpty(t, activeStatus.Error)
+	assert.Equal(t, kidCurrent, activeStatus.Kid)
+	assert.True(t, activeStatus.LastSuccess.Before(activeStatus.LastAttempt))
+	assert.Len(t, jwksProvider.GetJwks("default"), 3)
+	assert.NotNil(t, jwksProvider.GetDefaultRealm("default"))
+	failedReload, _ := jwksProvider.LastReload("default")
+	assert.True(t, failedReload.Before(activeStatus.LastAttempt), "a failed reload is not a successful reload")
+
+	// the active certificate is loaded again once it is complete
+	copySlotFiles(t, dir, "tls")
+
+	assert.Eventually(t, func() bool {
+		return jwksProvider.GetSlotStatus()[1].Loaded
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Empty(t, jwksProvider.GetSlotStatus()[1].Error)
+	recoveredReload, _ := jwksProvider.LastReload("default")
+	assert.True(t, recoveredReload.After(activeStatus.LastAttempt))
+
+	// a removed optional certificate is not retained
+	if err = os.Remove(path.Join(dir, "prev-tls.crt")); err != nil {
+		t.Fatalf("failed to remove certificate: %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(jwksProvider.GetJwks("default")) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.False(t, jwksProvider.GetSlotStatus()[2].Retained)
+
+	// a broken optional certificate does not hold back the successful reloads of the active one
+	if err = os.WriteFile(path.Join(dir, "prev-tls.crt"), []byte("-----BEGIN CERT"), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		return jwksProvider.GetSlotStatus()[2].Error != ""
+	}, 5*time.Second, 50*time.Millisecond)
+	prevStatus := jwksProvider.GetSlotStatus()[2]
+	degradedReload, _ := jwksProvider.LastReload("default")
+	assert.False(t, degradedReload.Before(prevStatus.LastAttempt))
+}
+
+func TestGetJwksWithCertificateChain(t *testing.T) {
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./file_provider_testdata",
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "chain-tls.crt",
+		KidFileNameActive:  "chain-tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+
+	jwKeySet := jwksProvider.GetJwks("default")
+	if !assert.Len(t, jwKeySet, 3) {
+		return
+	}
+
+	chainJwk := jwKeySet[1]
+	assert.Equal(t, "6B2E9D41-0F7C-4A83-B5D2-8C1E3F7A9D56", chainJwk.Kid)
+	assert.Len(t, chainJwk.X5c, 3)
+	assert.Len(t, jwKeySet[0].X5c, 1)
+}
+
+func TestFileProviderLastModified(t *testing.T) {
+	dir := t.TempDir()
+	copySlotFiles(t, dir, "tls", "prev-tls")
+
+	jwksConfig := newSlotTestConfig(dir)
+	jwksConfig.NextOptional = true
+
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	assert.False(t, jwksProvider.HasNextKey("default"))
+
+	lastModified := jwksProvider.LastModified("default")
+	assert.False(t, lastModified.IsZero())
+
+	// reloading unchanged certificates keeps the time of the last change
+	assert.Eventually(t, func() bool {
+		return jwksProvider.GetSlotStatus()[1].LastAttempt.After(lastModified)
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, lastModified, jwksProvider.LastModified("default"))
+
+	copySlotFiles(t, dir, "next-tls")
+
+	assert.Eventually(t, func() bool {
+		return jwksProvider.HasNextKey("default")
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.True(t, jwksProvider.LastModified("default").After(lastModified))
+}
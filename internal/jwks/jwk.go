@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+type Jwk struct {
+	Kid       string   `json:"kid"`
+	Kty       string   `json:"kty"`
+	Alg       string   `json:"alg"`
+	Use       string   `json:"use"`
+	N         string   `json:"n,omitempty"`
+	E         string   `json:"e,omitempty"`
+	Crv       string   `json:"crv,omitempty"`
+	X         string   `json:"x,omitempty"`
+	Y         string   `json:"y,omitempty"`
+	X5c       []string `json:"x5c"`
+	X5t       string   `json:"x5t"`
+	X5tS256   string   `json:"x5t#S256"`
+	PublicKey string   `json:"-"`
+
+	NotBefore time.Time `json:"-"` // start of the validity period of the certificate
+	NotAfter  time.Time `json:"-"` // end of the validity period of the certificate
+	Slot      string    `json:"-"` // slot the JWK is published in, e.g. 'next', 'active' or 'previous'
+	Source    string    `json:"-"` // file or secret the certificate was loaded from
+	LoadedAt  time.Time `json:"-"` // time the certificate was loaded
+}
+
+// IsExpiredAt returns whether the certificate of the JWK is expired at the given time.
+func (jwk *Jwk) IsExpiredAt(t time.Time) bool {
+	return !jwk.NotAfter.IsZero() && t.After(jwk.NotAfter)
+}
+
+// DefaultRealm is the public realm info of Keycloak. The service URLs are derived from the issuer URL of the realm
+// by the handler. Tokens are never revoked, so 'tokens-not-before' is always 0.
+type DefaultRealm struct {
+	Realm           string `json:"realm"`
+	PublicKey       string `json:"public_key"`
+	TokenService    string `json:"token-service,omitempty"`
+	AccountService  string `json:"account-service,omitempty"`
+	TokensNotBefore int64  `json:"tokens-not-before"`
+}
+
+// newJwk creates a JWK from the PEM encoded certificate chain with the leaf certificate first. If alg is empty,
+// the algorithm is derived from the public key.
+func newJwk(certByteArray []byte, kidByteArray []byte, alg string) (*Jwk, error) {
+	chain, err := ParseCertificateChain(certByteArray)
+	if err != nil {
+		return nil, err
+	}
+	cert := chain[0]
+
+	// Extract and format the public key
+	publicKeyString, err := PublicKey(cert)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Public Key: %w", err)
+	}
+
+	jwk := Jwk{
+		Kid:       parseKid(kidByteArray),
+		Use:       "sig",
+		X5c:       X5c(chain...),
+		X5t:       X5t(cert),
+		X5tS256:   X5tS256(cert),
+		PublicKey: publicKeyString,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		LoadedAt:  time.Now(),
+	}
+
+	if err = setKeyParameters(&jwk, cert, alg); err != nil {
+		return nil, fmt.Errorf("unable to create JWK: %w", err)
+	}
+
+	return &jwk, nil
+}
+
+// parseKid returns the key ID of the content of a key ID file or secret entry without surrounding whitespace, e.g.
+// the trailing newline added by editors.
+func parseKid(kidByteArray []byte) string {
+	return strings.TrimSpace(string(kidByteArray))
+}
+
+// readAlg returns the content of the optional algorithm file. If the file does not exist, defaultAlg is
+// returned, which itself may be empty.
+func readAlg(algFile string, defaultAlg string) (string, error) {
+	algByteArray, err := os.ReadFile(algFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return defaultAlg, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(algByteArray)), nil
+}
+
+// setKeyParameters sets the key type specific members (kty, alg and the public key parameters) of the JWK.
+// If alg is empty, the algorithm is derived from the public key.
+func setKeyParameters(jwk *Jwk, cert *x509.Certificate, alg string) error {
+	kty, err := Kty(cert)
+	if err != nil {
+		return err
+	}
+
+	resolvedAlg, err := ResolveAlg(cert, alg)
+	if err != nil {
+		return err
+	}
+
+	jwk.Kty = kty
+	jwk.Alg = resolvedAlg
+
+	switch kty {
+	case KtyRSA:
+		if jwk.E, err = E(cert); err != nil {
+			return err
+		}
+		if jwk.N, err = N(cert); err != nil {
+			return err
+		}
+	case KtyEC:
+		if jwk.Crv, err = Crv(cert); err != nil {
+			return err
+		}
+		if jwk.X, err = X(cert); err != nil {
+			return err
+		}
+		if jwk.Y, err = Y(cert); err != nil {
+			return err
+		}
+	case KtyOKP:
+		if jwk.Crv, err = Crv(cert); err != nil {
+			return err
+		}
+		if jwk.X, err = X(cert); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
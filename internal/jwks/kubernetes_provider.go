@@ -0,0 +1,322 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"issuer-service-go/internal/config"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// KubernetesProvider reads the certificates of the next, active and previous slot from 'kubernetes.io/tls'
+// secrets. The secrets are watched, so rotations are published as soon as the API server reports them.
+//
+// Every secret is watched by an informer of its own, which selects it by name, so only the configured secrets are
+// listed and cached, and the permissions can be restricted to them.
+//
+// The published keys are served from an immutable snapshot like the FileProvider does.
+type KubernetesProvider struct {
+	config *config.JwksKubernetesConfig
+
+	snapshots *snapshotPublisher
+
+	secretListers map[config.Type]listersv1.SecretNamespaceLister
+	hasSynced     []cache.InformerSynced
+
+	certsCacheMap map[config.Type]*Jwk
+	lastModified  time.Time
+
+	expiryMonitor *expiryMonitor
+
+	cacheMutex *sync.Mutex
+	// reloadMutex serializes the reloads triggered by the informers of the secrets, which run concurrently
+	reloadMutex *sync.Mutex
+}
+
+// NewKubernetesProvider starts watching the configured secrets and returns once the initial state was loaded.
+// The watch is stopped when ctx is done.
+func NewKubernetesProvider(
+	ctx context.Context,
+	client kubernetes.Interface,
+	k8sConfig *config.JwksKubernetesConfig,
+) (*KubernetesProvider, error) {
+	if k8sConfig.SecretNameActive == "" {
+		return nil, errors.New("failed to initialize KubernetesProvider: name of the active secret must be set")
+	}
+
+	kp := &KubernetesProvider{
+		config:        k8sConfig,
+		snapshots:     &snapshotPublisher{excludeExpired: k8sConfig.ExcludeExpired},
+		secretListers: make(map[config.Type]listersv1.SecretNamespaceLister),
+		certsCacheMap: make(map[config.Type]*Jwk),
+		expiryMonitor: newExpiryMonitor(k8sConfig.ExpiryWarnings),
+		cacheMutex:    &sync.Mutex{},
+		reloadMutex:   &sync.Mutex{},
+	}
+
+	var factories []informers.SharedInformerFactory
+	for _, certType := range []config.Type{config.Next, config.Active, config.Previous} {
+		secretName := k8sConfig.GetSecretName(certType)
+		if secretName == "" {
+			continue
+		}
+
+		factory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+			informers.WithNamespace(k8sConfig.Namespace),
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.FieldSelector = fields.OneTermEqualSelector("metadata.name", secretName).String()
+			}))
+		secretInformer := factory.Core().V1().Secrets()
+
+		_, err := secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    kp.onSecretEvent,
+			UpdateFunc: func(_, newObj any) { kp.onSecretEvent(newObj) },
+			DeleteFunc: kp.onSecretEvent,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize KubernetesProvider: %w", err)
+		}
+
+		kp.secretListers[certType] = secretInformer.Lister().Secrets(k8sConfig.Namespace)
+		kp.hasSynced = append(kp.hasSynced, secretInformer.Informer().HasSynced)
+		factories = append(factories, factory)
+	}
+
+	log.Info().Msgf("initializing JWKS cache from secrets in namespace %s...", k8sConfig.Namespace)
+	for _, factory := range factories {
+		factory.Start(ctx.Done())
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), kp.hasSynced...) {
+		return nil, errors.New("failed to initialize KubernetesProvider: secrets could not be synced")
+	}
+
+	if err := kp.updateCerts(); err != nil {
+		return nil, fmt.Errorf("failed to initialize KubernetesProvider: %w", err)
+	}
+	log.Info().Msgf("JWKS cache is initialized")
+
+	startExpiryCheck(ctx, kp.expiryMonitor, kp.cachedJwks)
+	return kp, nil
+}
+
+// GetJwks returns the JWKs of the next, active and previous secret, which are the same for every realm. Expired
+// JWKs are left out if CERT_EXCLUDE_EXPIRED is set. The returned slice is shared and must not be modified.
+func (kp *KubernetesProvider) GetJwks(_ string) []*Jwk {
+	return kp.currentSnapshot().Keys
+}
+
+func (kp *KubernetesProvider) cachedJwks() []*Jwk {
+	kp.cacheMutex.Lock()
+	defer kp.cacheMutex.Unlock()
+
+	return orderedJwks(kp.certsCacheMap)
+}
+
+// LoadedJwks returns the JWKs of the next, active and previous secret, including expired ones.
+func (kp *KubernetesProvider) LoadedJwks(_ string) []*Jwk {
+	return kp.cachedJwks()
+}
+
+func (kp *KubernetesProvider) LastModified(_ string) time.Time {
+	return kp.currentSnapshot().LastModified
+}
+
+func (kp *KubernetesProvider) HasNextKey(_ string) bool {
+	return kp.currentSnapshot().HasNextKey
+}
+
+// Snapshot returns the snapshot of the published keys, which is the same for every realm.
+func (kp *KubernetesProvider) Snapshot(_ string) *Snapshot {
+	return kp.currentSnapshot()
+}
+
+// currentSnapshot returns the snapshot of the published keys. It is only rebuilt if the secrets changed or if a
+// key expired that has to be excluded.
+func (kp *KubernetesProvider) currentSnapshot() *Snapshot {
+	return kp.snapshots.current(kp.cacheMutex, kp.publishSnapshot)
+}
+
+// publishSnapshot replaces the snapshot with the cached JWKs. It must be called while holding cacheMutex.
+func (kp *KubernetesProvider) publishSnapshot(now time.Time) error {
+	_, hasNextKey := kp.certsCacheMap[config.Next]
+	return kp.snapshots.publish(
+		orderedJwks(kp.certsCacheMap), kp.certsCacheMap[config.Active], kp.lastModified, hasNextKey, now)
+}
+
+// GetDefaultRealm returns the public key of the active JWK, or nil if there is none or it is expired.
+func (kp *KubernetesProvider) GetDefaultRealm(realm string) *DefaultRealm {
+	activeJwk := kp.currentSnapshot().activeJwk
+	if activeJwk == nil {
+		log.Warn().Msg("no active JWK available in cache for default realm")
+		return nil
+	}
+	if activeJwk.IsExpiredAt(time.Now()) {
+		log.Error().Msgf("active JWK with kid %s expired at %s", activeJwk.Kid, activeJwk.NotAfter)
+		return nil
+	}
+
+	return &DefaultRealm{
+		Realm:     realm,
+		PublicKey: activeJwk.PublicKey,
+	}
+}
+
+func (kp *KubernetesProvider) onSecretEvent(obj any) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	// the initial state is loaded by NewKubernetesProvider once all secrets are synced
+	secret, ok := obj.(*corev1.Secret)
+	if !ok || !kp.isSynced() || !kp.isWatchedSecret(secret.Name) {
+		return
+	}
+
+	log.Debug().Msgf("secret %s changed, updating the certificates...", secret.Name)
+	if err := kp.updateCerts(); err != nil {
+		log.Error().Msgf("failed to update certificates: %v", err)
+		return
+	}
+	log.Debug().Msg("certificates were updated successfully")
+}
+
+// isSynced returns whether the informers of all secrets are synced.
+func (kp *KubernetesProvider) isSynced() bool {
+	for _, hasSynced := range kp.hasSynced {
+		if !hasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (kp *KubernetesProvider) isWatchedSecret(name string) bool {
+	for _, certType := range []config.Type{config.Next, config.Active, config.Previous} {
+		if kp.config.GetSecretName(certType) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// updateCerts rebuilds the cache from the secrets known to the informers and records the result of the reload.
+func (kp *KubernetesProvider) updateCerts() error {
+	kp.reloadMutex.Lock()
+	defer kp.reloadMutex.Unlock()
+
+	err := kp.loadCerts()
+
+	kp.cacheMutex.Lock()
+	keysPerSlot := countSlots(kp.certsCacheMap)
+	kp.cacheMutex.Unlock()
+
+	keys := kp.cachedJwks()
+	recordReload(config.ProviderKubernetes, kp.config.Realm, keysPerSlot, keys, err)
+	kp.expiryMonitor.check(keys, time.Now())
+	return err
+}
+
+// loadCerts loads the secrets known to the informers into the cache. The active secret is mandatory, a missing
+// next or previous secret is skipped. If a next or previous secret cannot be parsed, its last successfully loaded
+// JWK is retained, like the FileProvider does. If the active secret cannot be loaded, the cache is left unchanged.
+func (kp *KubernetesProvider) loadCerts() error {
+	jwkActive, err := kp.generateCertInfo(config.Active)
+	if err != nil {
+		return err
+	}
+
+	certsCacheMap := make(map[config.Type]*Jwk)
+	addJwkToCache(certsCacheMap, config.Active, jwkActive)
+
+	for _, certType := range []config.Type{config.Previous, config.Next} {
+		if kp.config.GetSecretName(certType) == "" {
+			continue
+		}
+
+		jwk, jwkErr := kp.generateCertInfo(certType)
+		switch {
+		case jwkErr == nil:
+			addJwkToCache(certsCacheMap, certType, jwk)
+		case apierrors.IsNotFound(jwkErr):
+			log.Debug().Msgf("skipping secret %s: %v", kp.config.GetSecretName(certType), jwkErr)
+		default:
+			retainedJwk, retained := kp.cachedJwk(certType)
+			if !retained {
+				log.Warn().Msgf("skipping secret %s: %v", kp.config.GetSecretName(certType), jwkErr)
+				continue
+			}
+			log.Warn().Msgf("failed to load secret %s, retaining last known good JWK: %v",
+				kp.config.GetSecretName(certType), jwkErr)
+			addJwkToCache(certsCacheMap, certType, retainedJwk)
+		}
+	}
+
+	kp.cacheMutex.Lock()
+	defer kp.cacheMutex.Unlock()
+
+	if !keysEqual(orderedJwks(kp.certsCacheMap), orderedJwks(certsCacheMap)) {
+		kp.lastModified = time.Now()
+	}
+	kp.certsCacheMap = certsCacheMap
+	return kp.publishSnapshot(time.Now())
+}
+
+// cachedJwk returns the JWK that is currently published for the slot.
+func (kp *KubernetesProvider) cachedJwk(certType config.Type) (*Jwk, bool) {
+	kp.cacheMutex.Lock()
+	defer kp.cacheMutex.Unlock()
+
+	jwk, exists := kp.certsCacheMap[certType]
+	return jwk, exists
+}
+
+func (kp *KubernetesProvider) generateCertInfo(certType config.Type) (*Jwk, error) {
+	secretName := kp.config.GetSecretName(certType)
+	secret, err := kp.secretListers[certType].Get(secretName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s: %w", secretName, err)
+	}
+
+	if secret.Type != corev1.SecretTypeTLS {
+		return nil, fmt.Errorf("secret %s is of type %s instead of %s", secretName, secret.Type, corev1.SecretTypeTLS)
+	}
+
+	certByteArray, exists := secret.Data[corev1.TLSCertKey]
+	if !exists {
+		return nil, fmt.Errorf("secret %s does not contain %s", secretName, corev1.TLSCertKey)
+	}
+
+	kidByteArray, exists := secret.Data[kp.config.KidKey]
+	if !exists {
+		return nil, fmt.Errorf("secret %s does not contain %s", secretName, kp.config.KidKey)
+	}
+
+	alg := kp.config.Alg
+	if algByteArray, algExists := secret.Data[kp.config.AlgKey]; algExists {
+		alg = strings.TrimSpace(string(algByteArray))
+	}
+
+	jwk, err := newJwk(certByteArray, kidByteArray, alg)
+	if err != nil {
+		return nil, err
+	}
+	jwk.Slot = certType.String()
+	jwk.Source = kp.config.Namespace + "/" + secretName
+	return jwk, nil
+}
@@ -0,0 +1,300 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks_test
+
+import (
+	"fmt"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const (
+	testNamespace = "gateway"
+)
+
+func newTLSSecret(t *testing.T, name string, certFile string, kid string) *corev1.Secret {
+	t.Helper()
+
+	certByteArray, err := os.ReadFile(path.Join("./file_provider_testdata", certFile))
+	if err != nil {
+		t.Fatalf("failed to read certificate file: %v", err)
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certByteArray,
+			corev1.TLSPrivateKeyKey: []byte("not needed"),
+			"tls.kid":               []byte(kid),
+		},
+	}
+}
+
+func newKubernetesConfig() *config.JwksKubernetesConfig {
+	return &config.JwksKubernetesConfig{
+		Namespace:        testNamespace,
+		SecretNameNext:   "next-tls",
+		SecretNameActive: "tls",
+		SecretNamePrev:   "prev-tls",
+		KidKey:           "tls.kid",
+		AlgKey:           "tls.alg",
+	}
+}
+
+func TestNewKubernetesProvider(t *testing.T) {
+	tests := []struct {
+		name         string
+		secrets      []*corev1.Secret
+		err          bool
+		expectedKids []string
+	}{
+		{
+			name: "all secrets are published in the order next, active, previous",
+			secrets: []*corev1.Secret{
+				newTLSSecret(t, "next-tls", "next-tls.crt", kidNext),
+				newTLSSecret(t, "tls", "tls.crt", kidCurrent),
+				newTLSSecret(t, "prev-tls", "prev-tls.crt", kidPrevious1),
+				newTLSSecret(t, "unrelated", "ec-tls.crt", kidPrevious2),
+			},
+			expectedKids: []string{kidNext, kidCurrent, kidPrevious1},
+		},
+		{
+			name: "missing next and previous secrets are skipped",
+			secrets: []*corev1.Secret{
+				newTLSSecret(t, "tls", "tls.crt", kidCurrent),
+			},
+			expectedKids: []string{kidCurrent},
+		},
+		{
+			name: "error if the active secret does not exist",
+			secrets: []*corev1.Secret{
+				newTLSSecret(t, "next-tls", "next-tls.crt", kidNext),
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientset()
+			for _, secret := range tt.secrets {
+				_, err := client.CoreV1().Secrets(testNamespace).Create(t.Context(), secret, metav1.CreateOptions{})
+				if err != nil {
+					t.Fatalf("failed to create secret: %v", err)
+				}
+			}
+
+			jwksProvider, err := jwks.NewKubernetesProvider(t.Context(), client, newKubernetesConfig())
+			assert.Equalf(t, tt.err, err != nil, "expected error: %v, got: %v", tt.err, err)
+			if err != nil {
+				return
+			}
+
+			kids := make([]string, 0, len(tt.expectedKids))
+			for _, jwk := range jwksProvider.GetJwks("default") {
+				kids = append(kids, jwk.Kid)
+			}
+			assert.Equal(t, tt.expectedKids, kids)
+			assert.NotNil(t, jwksProvider.GetDefaultRealm("default"))
+		})
+	}
+}
+
+func TestKubernetesProviderWatchesSecrets(t *testing.T) {
+	client := fake.NewClientset(newTLSSecret(t, "tls", "tls.crt", kidCurrent))
+
+	jwksProvider, err := jwks.NewKubernetesProvider(t.Context(), client, newKubernetesConfig())
+	if err != nil {
+		t.Fatalf("failed to create JWKS kubernetes provider: %v", err)
+	}
+	assert.Len(t, jwksProvider.GetJwks("default"), 1)
+
+	// a new next key is published
+	nextSecret := newTLSSecret(t, "next-tls", "ec-tls.crt", kidPrevious2)
+	_, err = client.CoreV1().Secrets(testNamespace).Create(t.Context(), nextSecret, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		keys := jwksProvider.GetJwks("default")
+		return len(keys) == 2 && keys[0].Kid == kidPrevious2 && keys[0].Alg == "ES256"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the active key is rotated
+	activeSecret := newTLSSecret(t, "tls", "next-tls.crt", kidNext)
+	_, err = client.CoreV1().Secrets(testNamespace).Update(t.Context(), activeSecret, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("failed to update secret: %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		keys := jwksProvider.GetJwks("default")
+		return len(keys) == 2 && keys[1].Kid == kidNext
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the next key is removed
+	err = client.CoreV1().Secrets(testNamespace).Delete(t.Context(), "next-tls", metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatalf("failed to delete secret: %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(jwksProvider.GetJwks("default")) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestKubernetesProviderTrimsKid(t *testing.T) {
+	client := fake.NewClientset(newTLSSecret(t, "tls", "tls.crt", kidCurrent+"\n"))
+
+	jwksProvider, err := jwks.NewKubernetesProvider(t.Context(), client, newKubernetesConfig())
+	if err != nil {
+		t.Fatalf("failed to create JWKS kubernetes provider: %v", err)
+	}
+
+	assert.Equal(t, []string{kidCurrent}, kidsOf(jwksProvider.GetJwks("default")))
+}
+
+func TestKubernetesProviderIgnoresInvalidSecrets(t *testing.T) {
+	invalidSecret := newTLSSecret(t, "prev-tls", "prev-tls.crt", kidPrevious1)
+	invalidSecret.Type = corev1.SecretTypeOpaque
+
+	client := fake.NewClientset(newTLSSecret(t, "tls", "tls.crt", kidCurrent), invalidSecret)
+
+	jwksProvider, err := jwks.NewKubernetesProvider(t.Context(), client, newKubernetesConfig())
+	if err != nil {
+		t.Fatalf("failed to create JWKS kubernetes provider: %v", err)
+	}
+
+	keys := jwksProvider.GetJwks("default")
+	if assert.Len(t, keys, 1) {
+		assert.Equal(t, kidCurrent, keys[0].Kid)
+	}
+}
+
+func TestKubernetesProviderListsConfiguredSecretsOnly(t *testing.T) {
+	client := fake.NewClientset(newTLSSecret(t, "tls", "tls.crt", kidCurrent))
+
+	_, err := jwks.NewKubernetesProvider(t.Context(), client, newKubernetesConfig())
+	if err != nil {
+		t.Fatalf("failed to create JWKS kubernetes provider: %v", err)
+	}
+
+	var fieldSelectors []string
+	for _, action := range client.Actions() {
+		if listAction, ok := action.(k8stesting.ListAction); ok {
+			fieldSelectors = append(fieldSelectors, listAction.GetListRestrictions().Fields.String())
+		}
+	}
+	assert.ElementsMatch(t, []string{"metadata.name=next-tls", "metadata.name=tls", "metadata.name=prev-tls"},
+		fieldSelectors)
+}
+
+func TestKubernetesProviderRetainsLastKnownGoodJwk(t *testing.T) {
+	client := fake.NewClientset(
+		newTLSSecret(t, "tls", "tls.crt", kidCurrent),
+		newTLSSecret(t, "prev-tls", "prev-tls.crt", kidPrevious1),
+	)
+
+	jwksProvider, err := jwks.NewKubernetesProvider(t.Context(), client, newKubernetesConfig())
+	if err != nil {
+		t.Fatalf("failed to create JWKS kubernetes provider: %v", err)
+	}
+	assert.Len(t, jwksProvider.GetJwks("default"), 2)
+
+	// a broken previous secret does not remove the previous key
+	brokenSecret := newTLSSecret(t, "prev-tls", "prev-tls.crt", kidPrevious1)
+	brokenSecret.Data[corev1.TLSCertKey] = []byte("-----BEGIN CERT")
+	_, err = client.CoreV1().Secrets(testNamespace).Update(t.Context(), brokenSecret, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("failed to update secret: %v", err)
+	}
+	// the next key is published after the broken secret was processed
+	nextSecret := newTLSSecret(t, "next-tls", "next-tls.crt", kidNext)
+	_, err = client.CoreV1().Secrets(testNamespace).Create(t.Context(), nextSecret, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(jwksProvider.GetJwks("default")) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	// the secrets are watched independently, so the broken secret may be processed after the next one
+	assert.Never(t, func() bool {
+		return len(jwksProvider.GetJwks("default")) != 3
+	}, 300*time.Millisecond, 10*time.Millisecond)
+	assert.Equal(t, kidPrevious1, jwksProvider.GetJwks("default")[2].Kid)
+
+	// a deleted previous secret is not retained
+	err = client.CoreV1().Secrets(testNamespace).Delete(t.Context(), "prev-tls", metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatalf("failed to delete secret: %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(jwksProvider.GetJwks("default")) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestKubernetesProviderSerializesConcurrentUpdates(t *testing.T) {
+	client := fake.NewClientset(
+		newTLSSecret(t, "next-tls", "next-tls.crt", kidNext),
+		newTLSSecret(t, "tls", "tls.crt", kidCurrent),
+		newTLSSecret(t, "prev-tls", "prev-tls.crt", kidPrevious1),
+	)
+
+	jwksProvider, err := jwks.NewKubernetesProvider(t.Context(), client, newKubernetesConfig())
+	if err != nil {
+		t.Fatalf("failed to create JWKS kubernetes provider: %v", err)
+	}
+
+	// every secret is updated by its own informer, so the reloads of the updates run concurrently
+	var wg sync.WaitGroup
+	for _, secretName := range []string{"next-tls", "tls", "prev-tls"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 20 {
+				secret := newTLSSecret(t, secretName, "ec-tls.crt", fmt.Sprintf("%s-%d", secretName, i))
+				_, updateErr := client.CoreV1().Secrets(testNamespace).Update(t.Context(), secret, metav1.UpdateOptions{})
+				if updateErr != nil {
+					t.Errorf("failed to update secret: %v", updateErr)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the keys of the last updates are published and not overwritten by an earlier reload
+	expectedKids := []string{"next-tls-19", "tls-19", "prev-tls-19"}
+	kids := func() []string {
+		keys := jwksProvider.GetJwks("default")
+		kids := make([]string, 0, len(keys))
+		for _, jwk := range keys {
+			kids = append(kids, jwk.Kid)
+		}
+		return kids
+	}
+	assert.Eventually(t, func() bool {
+		return slices.Equal(expectedKids, kids())
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Never(t, func() bool {
+		return !slices.Equal(expectedKids, kids())
+	}, 300*time.Millisecond, 10*time.Millisecond)
+}
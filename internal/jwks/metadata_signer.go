@@ -0,0 +1,255 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"issuer-service-go/internal/config"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	metadataSignerSchedulerName = "Metadata Signer - Scheduler"
+
+	// maxSignedTokens limits the number of cached tokens, because the issuer is derived from request headers.
+	maxSignedTokens = 1024
+)
+
+// MetadataSigner signs metadata (e.g. the discovery document or the JWKS) as JWT with a separate metadata signing
+// key, which is loaded from mounted files in the same way as the certificates of the FileProvider.
+//
+// Signed tokens are cached and only signed again after half of their lifetime or if the key changes, so the
+// responses stay cacheable by clients even though ECDSA and RSASSA-PSS signatures are randomized.
+type MetadataSigner struct {
+	config *config.MetadataSigningConfig
+
+	key atomic.Pointer[signingKey]
+
+	tokens      map[[sha256.Size]byte]signedToken
+	tokensMutex *sync.Mutex
+
+	isSchedulerRunning bool
+}
+
+type signingKey struct {
+	kid    string
+	alg    string
+	x5c    []string
+	signer crypto.Signer
+}
+
+type signedToken struct {
+	token    string
+	key      *signingKey
+	signedAt time.Time
+}
+
+// NewMetadataSigner loads the signing key and reloads it in the background until ctx is done.
+func NewMetadataSigner(ctx context.Context, signingConfig *config.MetadataSigningConfig) (*MetadataSigner, error) {
+	if signingConfig.MountedPath == "" {
+		return nil, errors.New("failed to initialize MetadataSigner: METADATA_SIGNING_MOUNT_PATH must be set")
+	}
+
+	ms := &MetadataSigner{
+		config:      signingConfig,
+		tokens:      make(map[[sha256.Size]byte]signedToken),
+		tokensMutex: &sync.Mutex{},
+	}
+	if err := ms.updateKey(); err != nil {
+		return nil, fmt.Errorf("failed to initialize MetadataSigner: %w", err)
+	}
+
+	ms.isSchedulerRunning = runScheduler(ctx, metadataSignerSchedulerName, signingConfig.UpdateInterval, func() {
+		if err := ms.updateKey(); err != nil {
+			log.Error().Msgf("failed to update metadata signing key, retaining last known good key: %v", err)
+		}
+	})
+	return ms, nil
+}
+
+// Sign returns the claims as JWT with the given 'typ' header, which is left out if typ is empty. The 'iat' and
+// 'exp' claims are added. The certificate chain of the signing key is sent in the 'x5c' header, so consumers can
+// verify the signature against their trust anchor.
+func (ms *MetadataSigner) Sign(typ string, claims map[string]any) (string, error) {
+	key := ms.key.Load()
+
+	claimsByteArray, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %w", err)
+	}
+	cacheKey := sha256.Sum256(append([]byte(typ+"\x00"), claimsByteArray...))
+
+	ms.tokensMutex.Lock()
+	defer ms.tokensMutex.Unlock()
+
+	now := time.Now()
+	if cached, exists := ms.tokens[cacheKey]; exists && cached.key == key && !ms.isRenewalDue(cached, now) {
+		return cached.token, nil
+	}
+
+	token, err := ms.signToken(key, typ, claims, now)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ms.tokens) >= maxSignedTokens {
+		clear(ms.tokens)
+	}
+	ms.tokens[cacheKey] = signedToken{token: token, key: key, signedAt: now}
+	return token, nil
+}
+
+func (ms *MetadataSigner) IsSchedulerRunning() bool {
+	return ms.isSchedulerRunning
+}
+
+// isRenewalDue returns whether half of the lifetime of the token has passed.
+func (ms *MetadataSigner) isRenewalDue(cached signedToken, now time.Time) bool {
+	return ms.config.TokenLifetime > 0 && now.Sub(cached.signedAt) >= ms.config.TokenLifetime/2
+}
+
+func (ms *MetadataSigner) signToken(key *signingKey, typ string, claims map[string]any, now time.Time) (string, error) {
+	header := map[string]any{"alg": key.alg, "kid": key.kid, "x5c": key.x5c}
+	if typ != "" {
+		header["typ"] = typ
+	}
+
+	claims = maps.Clone(claims)
+	claims["iat"] = now.Unix()
+	if ms.config.TokenLifetime > 0 {
+		claims["exp"] = now.Add(ms.config.TokenLifetime).Unix()
+	}
+
+	headerByteArray, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal header: %w", err)
+	}
+	claimsByteArray, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerByteArray) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsByteArray)
+	signature, err := sign(key, []byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign metadata: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// updateKey loads the metadata signing key. If it changed, tokens signed with the previous key are not served
+// anymore.
+func (ms *MetadataSigner) updateKey() error {
+	key, err := loadSigningKey(ms.config)
+	if err != nil {
+		return err
+	}
+
+	if current := ms.key.Load(); current != nil && current.kid == key.kid && current.alg == key.alg &&
+		slices.Equal(current.x5c, key.x5c) {
+		return nil
+	}
+
+	ms.key.Store(key)
+	log.Info().Msgf("metadata signing key with kid %s and algorithm %s is loaded", key.kid, key.alg)
+	return nil
+}
+
+func loadSigningKey(signingConfig *config.MetadataSigningConfig) (*signingKey, error) {
+	certByteArray, err := os.ReadFile(signingConfig.GetCertFile())
+	if err != nil {
+		return nil, err
+	}
+	chain, err := ParseCertificateChain(certByteArray)
+	if err != nil {
+		return nil, err
+	}
+
+	keyByteArray, err := os.ReadFile(signingConfig.GetKeyFile())
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ParsePrivateKey(keyByteArray)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := chain[0].PublicKey.(interface{ Equal(x crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(signer.Public()) {
+		return nil, errors.New("private key does not match the certificate")
+	}
+
+	kidByteArray, err := os.ReadFile(signingConfig.GetKidFile())
+	if err != nil {
+		return nil, err
+	}
+
+	alg, err := readAlg(signingConfig.GetAlgFile(), signingConfig.Alg)
+	if err != nil {
+		return nil, err
+	}
+	alg, err = ResolveAlg(chain[0], alg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signingKey{
+		kid:    parseKid(kidByteArray),
+		alg:    alg,
+		x5c:    X5c(chain...),
+		signer: signer,
+	}, nil
+}
+
+// sign creates the JWS signature of the signing input. ECDSA signatures are the concatenation of R and S.
+func sign(key *signingKey, signingInput []byte) ([]byte, error) {
+	if edKey, ok := key.signer.(ed25519.PrivateKey); ok {
+		return ed25519.Sign(edKey, signingInput), nil
+	}
+
+	hash, err := Hash(key.alg)
+	if err != nil {
+		return nil, err
+	}
+	hasher := hash.New()
+	hasher.Write(signingInput)
+	digest := hasher.Sum(nil)
+
+	switch signer := key.signer.(type) {
+	case *rsa.PrivateKey:
+		if strings.HasPrefix(key.alg, "PS") {
+			return rsa.SignPSS(rand.Reader, signer, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.SignPKCS1v15(rand.Reader, signer, hash, digest)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, signer, digest)
+		if err != nil {
+			return nil, err
+		}
+		size := (signer.Curve.Params().BitSize + 7) / 8 //nolint:mnd // bits to bytes
+		return append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...), nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", key.signer)
+}
@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/testutil"
+	"issuer-service-go/internal/validation"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	signingKid = "0B7E4F2A-6C1D-4A8E-B3F5-9D2C7E1A4B60"
+)
+
+// writeSigningKey writes the certificate, private key and key ID of a metadata signing key to dir.
+func writeSigningKey(t *testing.T, dir string, signer crypto.Signer) {
+	t.Helper()
+
+	certificate := testutil.NewCertificate(t, testutil.CertificateOptions{Key: signer})
+
+	files := map[string][]byte{
+		"tls.crt": certificate.CertPEM,
+		"tls.key": certificate.KeyPEM,
+		"tls.kid": []byte(signingKid),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(path.Join(dir, name), content, 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func newSigningTestConfig(dir string) *config.MetadataSigningConfig {
+	return &config.MetadataSigningConfig{
+		MountedPath:   dir,
+		CertFileName:  "tls.crt",
+		KeyFileName:   "tls.key",
+		KidFileName:   "tls.kid",
+		TokenLifetime: time.Hour,
+	}
+}
+
+// verifySignedToken verifies the token with the certificate of its 'x5c' header and returns its header.
+func verifySignedToken(t *testing.T, token string, issuer string) map[string]any {
+	t.Helper()
+
+	headerByteArray, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if err != nil {
+		t.Fatalf("failed to decode header: %v", err)
+	}
+	var header map[string]any
+	if err = json.Unmarshal(headerByteArray, &header); err != nil {
+		t.Fatalf("failed to unmarshal header: %v", err)
+	}
+
+	x5c, _ := header["x5c"].([]any)
+	if !assert.Len(t, x5c, 1) {
+		return header
+	}
+	jwk := &jwks.Jwk{Kid: signingKid, Alg: header["alg"].(string), X5c: []string{x5c[0].(string)}}
+	diagnosis := validation.Validate(token, []*jwks.Jwk{jwk}, validation.Expectations{Issuer: issuer, Now: time.Now()})
+	assert.Truef(t, diagnosis.Valid, "signed token is invalid: %+v", diagnosis.Checks)
+	return header
+}
+
+func TestMetadataSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		signer      crypto.Signer
+		alg         string
+		expectedAlg string
+	}{
+		{name: "RSA key", signer: rsaKey, expectedAlg: "RS256"},
+		{name: "RSA key with RSASSA-PSS", signer: rsaKey, alg: "PS384", expectedAlg: "PS384"},
+		{name: "EC key", signer: ecKey, expectedAlg: "ES384"},
+		{name: "Ed25519 key", signer: edKey, expectedAlg: "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeSigningKey(t, dir, tt.signer)
+			signingConfig := newSigningTestConfig(dir)
+			signingConfig.Alg = tt.alg
+
+			metadataSigner, err := jwks.NewMetadataSigner(t.Context(), signingConfig)
+			if err != nil {
+				t.Fatalf("failed to create metadata signer: %v", err)
+			}
+
+			token, err := metadataSigner.Sign("jwk-set+jwt", map[string]any{"iss": "https://example.com"})
+			if err != nil {
+				t.Fatalf("failed to sign metadata: %v", err)
+			}
+			header := verifySignedToken(t, token, "https://example.com")
+			assert.Equal(t, tt.expectedAlg, header["alg"])
+			assert.Equal(t, signingKid, header["kid"])
+			assert.Equal(t, "jwk-set+jwt", header["typ"])
+		})
+	}
+}
+
+func TestMetadataSignerCachesTokens(t *testing.T) {
+	dir := t.TempDir()
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	writeSigningKey(t, dir, ecKey)
+
+	metadataSigner, err := jwks.NewMetadataSigner(t.Context(), newSigningTestConfig(dir))
+	if err != nil {
+		t.Fatalf("failed to create metadata signer: %v", err)
+	}
+
+	claims := map[string]any{"iss": "https://example.com"}
+	token, err := metadataSigner.Sign("", claims)
+	if err != nil {
+		t.Fatalf("failed to sign metadata: %v", err)
+	}
+	assert.NotContains(t, claims, "iat", "the claims must not be modified")
+
+	// ECDSA signatures are randomized, so an equal token proves that it was cached
+	cachedToken, err := metadataSigner.Sign("", map[string]any{"iss": "https://example.com"})
+	if err != nil {
+		t.Fatalf("failed to sign metadata: %v", err)
+	}
+	assert.Equal(t, token, cachedToken)
+
+	otherToken, err := metadataSigner.Sign("", map[string]any{"iss": "https://other.example.com"})
+	if err != nil {
+		t.Fatalf("failed to sign metadata: %v", err)
+	}
+	assert.NotEqual(t, token, otherToken)
+	header := verifySignedToken(t, otherToken, "https://other.example.com")
+	assert.NotContains(t, header, "typ")
+}
+
+func TestNewMetadataSignerErrors(t *testing.T) {
+	_, err := jwks.NewMetadataSigner(t.Context(), &config.MetadataSigningConfig{})
+	assert.Error(t, err, "the mount path is required")
+
+	dir := t.TempDir()
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	writeSigningKey(t, dir, ecKey)
+
+	// the private key of another certificate
+	otherDir := t.TempDir()
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	writeSigningKey(t, otherDir, otherKey)
+	otherKeyByteArray, err := os.ReadFile(path.Join(otherDir, "tls.key"))
+	if err != nil {
+		t.Fatalf("failed to read private key: %v", err)
+	}
+	if err = os.WriteFile(path.Join(dir, "tls.key"), otherKeyByteArray, 0o600); err != nil {
+		t.Fatalf("failed to write private key: %v", err)
+	}
+
+	_, err = jwks.NewMetadataSigner(t.Context(), newSigningTestConfig(dir))
+	assert.ErrorContains(t, err, "private key does not match the certificate")
+
+	signingConfig := newSigningTestConfig(otherDir)
+	signingConfig.Alg = "RS256"
+	_, err = jwks.NewMetadataSigner(t.Context(), signingConfig)
+	assert.Error(t, err, "the algorithm must match the key")
+}
@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/metrics"
+	"time"
+)
+
+// recordReload records the result of a reload and the published keys of the provider of the realm.
+func recordReload(provider string, realm string, keysPerSlot map[string]int, keys []*Jwk, err error) {
+	expiries := make(map[string]time.Time, len(keys))
+	for _, jwk := range keys {
+		expiries[jwk.Kid] = jwk.NotAfter
+	}
+
+	metrics.SetKeys(provider, realm, keysPerSlot, expiries)
+	metrics.RecordReload(provider, realm, err)
+}
+
+// countSlots returns the number of keys per slot of a cache with next, active and previous slot.
+func countSlots(certsCacheMap map[config.Type]*Jwk) map[string]int {
+	keysPerSlot := make(map[string]int, len(certsCacheMap))
+	for _, certType := range []config.Type{config.Next, config.Active, config.Previous} {
+		keysPerSlot[certType.String()] = 0
+		if _, exists := certsCacheMap[certType]; exists {
+			keysPerSlot[certType.String()] = 1
+		}
+	}
+	return keysPerSlot
+}
@@ -4,7 +4,94 @@
 
 package jwks
 
+import (
+	"errors"
+	"issuer-service-go/internal/config"
+	"slices"
+	"time"
+)
+
+// Provider publishes the keys of the realms. Providers reading a single key source serve the same keys for
+// every realm, RealmProvider serves the keys of a separate provider per realm.
 type Provider interface {
-	GetJwks() []*Jwk
+	// GetJwks returns the published keys of the realm.
+	GetJwks(realm string) []*Jwk
 	GetDefaultRealm(realm string) *DefaultRealm
+	// LastModified returns the time of the last change of the published keys of the realm.
+	LastModified(realm string) time.Time
+	// HasNextKey returns whether a key is published for the realm that is going to be used for signing after
+	// the next rotation.
+	HasNextKey(realm string) bool
+}
+
+// ReloadStatusProvider is implemented by providers that reload their keys, so a stuck reload can be detected.
+type ReloadStatusProvider interface {
+	// LastReload returns the time of the last successful reload of the keys of the realm and the interval in
+	// which they are reloaded, which is 0 if they are not reloaded periodically.
+	LastReload(realm string) (time.Time, time.Duration)
+}
+
+// SlotStatusProvider is implemented by providers that load their keys into slots, so a slot that failed to load
+// can be reported while the other slots are served.
+type SlotStatusProvider interface {
+	// SlotStatus returns the load status of every slot of the realm.
+	SlotStatus(realm string) []SlotStatus
+}
+
+// InventoryProvider is implemented by providers that can return every loaded key, including the expired ones that
+// are not published if CERT_EXCLUDE_EXPIRED is set.
+type InventoryProvider interface {
+	// LoadedJwks returns every loaded key of the realm, whether it is published or not.
+	LoadedJwks(realm string) []*Jwk
+}
+
+// ErrReloadNotSupported is returned if the keys of a realm cannot be reloaded on demand.
+var ErrReloadNotSupported = errors.New("the JWKS provider does not support reloading on demand")
+
+// Reloader is implemented by providers whose keys can be reloaded on demand.
+type Reloader interface {
+	// Reload loads the keys of the realm synchronously and returns the load status of every slot. The error
+	// contains the failures of all slots.
+	Reload(realm string) ([]SlotStatus, error)
+}
+
+// LoadedJwks returns every loaded key of the realm, or the published keys if the provider does not implement
+// InventoryProvider.
+func LoadedJwks(provider Provider, realm string) []*Jwk {
+	if inventoryProvider, ok := provider.(InventoryProvider); ok {
+		return inventoryProvider.LoadedJwks(realm)
+	}
+	return provider.GetJwks(realm)
+}
+
+// SigningAlgs returns the sorted union of the algorithms of the given JWKs.
+func SigningAlgs(keys []*Jwk) []string {
+	algs := make([]string, 0, len(keys))
+	for _, jwk := range keys {
+		if jwk.Alg != "" {
+			algs = append(algs, jwk.Alg)
+		}
+	}
+	slices.Sort(algs)
+	return slices.Compact(algs)
+}
+
+// orderedJwks returns the JWKs of a cache with next, active and previous slot in this order.
+func orderedJwks(certsCacheMap map[config.Type]*Jwk) []*Jwk {
+	keyOrder := []config.Type{config.Next, config.Active, config.Previous}
+
+	values := make([]*Jwk, 0, len(keyOrder))
+	for _, key := range keyOrder {
+		if jwk, exists := certsCacheMap[key]; exists {
+			values = append(values, jwk)
+		}
+	}
+	return values
+}
+
+// keysEqual returns whether both lists contain the same keys with the same certificates in the same order.
+func keysEqual(a []*Jwk, b []*Jwk) bool {
+	return slices.EqualFunc(a, b, func(x *Jwk, y *Jwk) bool {
+		return x.Kid == y.Kid && x.Alg == y.Alg && slices.Equal(x.X5c, y.X5c)
+	})
 }
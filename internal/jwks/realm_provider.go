@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RealmProvider serves the keys of every realm from the provider configured for the realm. Realms without a
+// provider of their own are served by the default provider, which may be nil if every realm has its own keys.
+type RealmProvider struct {
+	defaultProvider Provider
+	realmProviders  map[string]Provider
+}
+
+func NewRealmProvider(defaultProvider Provider, realmProviders map[string]Provider) *RealmProvider {
+	return &RealmProvider{
+		defaultProvider: defaultProvider,
+		realmProviders:  maps.Clone(realmProviders),
+	}
+}
+
+// GetJwks returns the keys of the realm, or no keys if no provider is configured for the realm.
+func (rp *RealmProvider) GetJwks(realm string) []*Jwk {
+	provider := rp.providerOf(realm)
+	if provider == nil {
+		return []*Jwk{}
+	}
+	return provider.GetJwks(realm)
+}
+
+// LoadedJwks returns every loaded key of the realm, including the ones that are not published.
+func (rp *RealmProvider) LoadedJwks(realm string) []*Jwk {
+	provider := rp.providerOf(realm)
+	if provider == nil {
+		return []*Jwk{}
+	}
+	return LoadedJwks(provider, realm)
+}
+
+// GetDefaultRealm returns the public key of the active key of the realm, or nil if there is none.
+func (rp *RealmProvider) GetDefaultRealm(realm string) *DefaultRealm {
+	provider := rp.providerOf(realm)
+	if provider == nil {
+		log.Warn().Msgf("no JWKS provider configured for realm %s", realm)
+		return nil
+	}
+	return provider.GetDefaultRealm(realm)
+}
+
+func (rp *RealmProvider) LastModified(realm string) time.Time {
+	provider := rp.providerOf(realm)
+	if provider == nil {
+		return time.Time{}
+	}
+	return provider.LastModified(realm)
+}
+
+func (rp *RealmProvider) HasNextKey(realm string) bool {
+	provider := rp.providerOf(realm)
+	if provider == nil {
+		return false
+	}
+	return provider.HasNextKey(realm)
+}
+
+// Snapshot returns the snapshot of the keys of the realm, or nil if the provider of the realm does not publish
+// snapshots.
+func (rp *RealmProvider) Snapshot(realm string) *Snapshot {
+	if snapshotProvider, ok := rp.providerOf(realm).(SnapshotProvider); ok {
+		return snapshotProvider.Snapshot(realm)
+	}
+	return nil
+}
+
+// LastReload returns the last reload of the keys of the realm, or the zero time and interval if the provider of
+// the realm does not report its reloads.
+func (rp *RealmProvider) LastReload(realm string) (time.Time, time.Duration) {
+	if reloadStatusProvider, ok := rp.providerOf(realm).(ReloadStatusProvider); ok {
+		return reloadStatusProvider.LastReload(realm)
+	}
+	return time.Time{}, 0
+}
+
+// SlotStatus returns the load status of the slots of the realm, or nil if the provider of the realm has no slots.
+func (rp *RealmProvider) SlotStatus(realm string) []SlotStatus {
+	if slotStatusProvider, ok := rp.providerOf(realm).(SlotStatusProvider); ok {
+		return slotStatusProvider.SlotStatus(realm)
+	}
+	return nil
+}
+
+// Reload reloads the keys of the realm, or returns ErrReloadNotSupported if its provider cannot reload on demand.
+func (rp *RealmProvider) Reload(realm string) ([]SlotStatus, error) {
+	if reloader, ok := rp.providerOf(realm).(Reloader); ok {
+		return reloader.Reload(realm)
+	}
+	return nil, ErrReloadNotSupported
+}
+
+// Realms returns the sorted realms with a provider of their own.
+func (rp *RealmProvider) Realms() []string {
+	return slices.Sorted(maps.Keys(rp.realmProviders))
+}
+
+// ProviderRealms returns the realms whose keys are served by separate providers. The empty realm stands for the
+// keys of all realms without keys of their own and is left out if there are none.
+func ProviderRealms(provider Provider) []string {
+	realmProvider, ok := provider.(*RealmProvider)
+	if !ok {
+		return []string{""}
+	}
+
+	realms := realmProvider.Realms()
+	if realmProvider.defaultProvider != nil {
+		realms = slices.Insert(realms, 0, "")
+	}
+	return realms
+}
+
+func (rp *RealmProvider) providerOf(realm string) Provider {
+	if provider, exists := rp.realmProviders[realm]; exists {
+		return provider
+	}
+	return rp.defaultProvider
+}
@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks_test
+
+import (
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newRealmTestConfig returns the configuration of a file provider with the active key kidCurrent and of the
+// realm 'realm-b' with the active key kidPrevious2.
+func newRealmTestConfig(t *testing.T) *config.JwksFileConfig {
+	t.Helper()
+
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := time.Now().Add(time.Hour)
+
+	defaultDir := t.TempDir()
+	writeCertificate(t, defaultDir, "tls", kidCurrent, notBefore, notAfter)
+	realmDir := t.TempDir()
+	writeCertificate(t, realmDir, "tls", kidPrevious2, notBefore, notAfter)
+	writeCertificate(t, realmDir, "next-tls", kidNext, notBefore, notAfter)
+
+	jwksConfig := newSlotTestConfig(defaultDir)
+	jwksConfig.UpdateInterval = 0
+	jwksConfig.NextOptional = true
+	jwksConfig.PrevOptional = true
+	jwksConfig.RealmMountPaths = map[string]string{"realm-b": realmDir}
+	return jwksConfig
+}
+
+func TestRealmProvider(t *testing.T) {
+	jwksConfig := newRealmTestConfig(t)
+	assert.Equal(t, []string{"realm-b"}, jwksConfig.Realms())
+
+	defaultProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	realmProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig.ForRealm("realm-b"))
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider of realm: %v", err)
+	}
+
+	jwksProvider := jwks.NewRealmProvider(defaultProvider, map[string]jwks.Provider{"realm-b": realmProvider})
+	assert.Equal(t, []string{"realm-b"}, jwksProvider.Realms())
+
+	// realms without keys of their own are served the default keys
+	assert.Equal(t, []string{kidCurrent}, kidsOf(jwksProvider.GetJwks("default")))
+	assert.False(t, jwksProvider.HasNextKey("default"))
+	assert.Equal(t, defaultProvider.GetJwks("default")[0].PublicKey,
+		jwksProvider.GetDefaultRealm("default").PublicKey)
+	assert.Same(t, defaultProvider.Snapshot("default"), jwksProvider.Snapshot("default"))
+
+	assert.Equal(t, []string{kidNext, kidPrevious2}, kidsOf(jwksProvider.GetJwks("realm-b")))
+	assert.True(t, jwksProvider.HasNextKey("realm-b"))
+	assert.Equal(t, realmProvider.GetJwks("realm-b")[1].PublicKey,
+		jwksProvider.GetDefaultRealm("realm-b").PublicKey)
+	assert.Equal(t, realmProvider.LastModified("realm-b"), jwksProvider.LastModified("realm-b"))
+	assert.Same(t, realmProvider.Snapshot("realm-b"), jwksProvider.Snapshot("realm-b"))
+
+	realmReload, _ := realmProvider.LastReload("realm-b")
+	lastReload, interval := jwksProvider.LastReload("realm-b")
+	assert.Equal(t, realmReload, lastReload)
+	assert.Zero(t, interval, "the certificates are never reloaded")
+
+	assert.Equal(t, []string{"", "realm-b"}, jwks.ProviderRealms(jwksProvider))
+	assert.Equal(t, []string{""}, jwks.ProviderRealms(defaultProvider))
+}
+
+func TestRealmProviderWithoutDefault(t *testing.T) {
+	jwksConfig := newRealmTestConfig(t)
+
+	realmProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig.ForRealm("realm-b"))
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider of realm: %v", err)
+	}
+
+	jwksProvider := jwks.NewRealmProvider(nil, map[string]jwks.Provider{"realm-b": realmProvider})
+
+	assert.Len(t, jwksProvider.GetJwks("realm-b"), 2)
+
+	assert.NotNil(t, jwksProvider.GetJwks("default"))
+	assert.Empty(t, jwksProvider.GetJwks("default"))
+	assert.Nil(t, jwksProvider.GetDefaultRealm("default"))
+	assert.Nil(t, jwksProvider.Snapshot("default"))
+	assert.True(t, jwksProvider.LastModified("default").IsZero())
+	assert.False(t, jwksProvider.HasNextKey("default"))
+
+	lastReload, interval := jwksProvider.LastReload("default")
+	assert.True(t, lastReload.IsZero())
+	assert.Zero(t, interval)
+	assert.Equal(t, []string{"realm-b"}, jwks.ProviderRealms(jwksProvider))
+}
+
+func TestKubernetesRealmConfig(t *testing.T) {
+	k8sConfig := newKubernetesConfig()
+	k8sConfig.RealmSecretNamesActive = map[string]string{"realm-b": "realm-b-tls", "realm-a": "realm-a-tls"}
+	k8sConfig.RealmSecretNamesPrev = map[string]string{"realm-b": "realm-b-prev-tls"}
+	assert.Equal(t, []string{"realm-a", "realm-b"}, k8sConfig.Realms())
+
+	client := fake.NewClientset(
+		newTLSSecret(t, "tls", "tls.crt", kidCurrent),
+		newTLSSecret(t, "realm-b-tls", "ec-tls.crt", kidPrevious2),
+		newTLSSecret(t, "realm-b-prev-tls", "prev-tls.crt", kidPrevious1),
+	)
+
+	defaultProvider, err := jwks.NewKubernetesProvider(t.Context(), client, k8sConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS kubernetes provider: %v", err)
+	}
+	realmProvider, err := jwks.NewKubernetesProvider(t.Context(), client, k8sConfig.ForRealm("realm-b"))
+	if err != nil {
+		t.Fatalf("failed to create JWKS kubernetes provider of realm: %v", err)
+	}
+
+	jwksProvider := jwks.NewRealmProvider(defaultProvider, map[string]jwks.Provider{"realm-b": realmProvider})
+	assert.Equal(t, []string{kidCurrent}, kidsOf(jwksProvider.GetJwks("default")))
+	assert.Equal(t, []string{kidPrevious2, kidPrevious1}, kidsOf(jwksProvider.GetJwks("realm-b")))
+}
+
+// TestRealmProviderSecretsDoNotLeak ensures that a realm only publishes the secrets configured for it.
+func TestRealmProviderSecretsDoNotLeak(t *testing.T) {
+	k8sConfig := newKubernetesConfig()
+	k8sConfig.RealmSecretNamesActive = map[string]string{"realm-b": "realm-b-tls"}
+
+	realmConfig := k8sConfig.ForRealm("realm-b")
+	assert.Equal(t, "realm-b-tls", realmConfig.SecretNameActive)
+	assert.Empty(t, realmConfig.SecretNameNext)
+	assert.Empty(t, realmConfig.SecretNamePrev)
+	assert.Equal(t, "realm-b", realmConfig.Realm)
+	assert.Empty(t, k8sConfig.Realm)
+
+	client := fake.NewClientset(
+		newTLSSecret(t, "next-tls", "next-tls.crt", kidNext),
+		newTLSSecret(t, "realm-b-tls", "ec-tls.crt", kidPrevious2),
+	)
+
+	jwksProvider, err := jwks.NewKubernetesProvider(t.Context(), client, realmConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS kubernetes provider of realm: %v", err)
+	}
+	assert.Equal(t, []string{kidPrevious2}, kidsOf(jwksProvider.GetJwks("realm-b")))
+}
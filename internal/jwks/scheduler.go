@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// runScheduler executes the task every updateInterval seconds in the background until ctx is done.
+// It returns false if the scheduler is deactivated, because updateInterval is 0.
+func runScheduler(ctx context.Context, name string, updateInterval int, task func()) bool {
+	if updateInterval == 0 {
+		log.Info().Msgf("%s is deactivated", name)
+		return false
+	}
+
+	log.Info().Msgf("starting %s ...", name)
+	ticker := time.NewTicker(time.Duration(updateInterval) * time.Second)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info().Msgf("%s stopped", name)
+				return
+			case <-ticker.C:
+				task()
+			}
+		}
+	}()
+
+	log.Info().Msgf("%s started", name)
+	return true
+}
@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunSchedulerStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+
+	var executions atomic.Int32
+	assert.True(t, runScheduler(ctx, "test scheduler", 1, func() { executions.Add(1) }))
+	assert.Eventually(t, func() bool { return executions.Load() > 0 }, 3*time.Second, 10*time.Millisecond)
+
+	cancel()
+	stoppedAt := executions.Load()
+	time.Sleep(1500 * time.Millisecond)
+	assert.LessOrEqual(t, executions.Load(), stoppedAt+1, "the task must not be executed after ctx is done")
+}
+
+func TestRunSchedulerIsDeactivated(t *testing.T) {
+	assert.False(t, runScheduler(t.Context(), "test scheduler", 0, func() {}))
+}
@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/rs/zerolog/log"
+)
+
+// SnapshotProvider is implemented by providers that publish their keys as immutable snapshots, so the JWKS
+// response does not have to be rendered per request. Snapshot returns nil if the realm is not served from a
+// snapshot.
+type SnapshotProvider interface {
+	Snapshot(realm string) *Snapshot
+}
+
+// Snapshot is an immutable view of the published keys with the pre-rendered JWKS response. It is replaced
+// as a whole whenever the published keys change, so none of its fields must be modified.
+type Snapshot struct {
+	Keys []*Jwk
+
+	JSON   []byte // JWKS response
+	Gzip   []byte // gzip compressed JWKS response
+	Brotli []byte // brotli compressed JWKS response
+	ETag   string // strong ETag of the uncompressed JWKS response
+
+	LastModified time.Time
+	HasNextKey   bool
+
+	activeJwk *Jwk
+	// staleAt is the time at which the first published key expires and has to be excluded, zero if the
+	// snapshot does not become stale
+	staleAt time.Time
+}
+
+// snapshotPublisher publishes the keys of a provider as snapshots. If expired keys are excluded, the snapshot is
+// rebuilt once one of the published keys expires.
+type snapshotPublisher struct {
+	snapshot       atomic.Pointer[Snapshot]
+	excludeExpired bool
+}
+
+// publish replaces the snapshot with the given keys, which are ordered as they are served.
+func (p *snapshotPublisher) publish(
+	keys []*Jwk,
+	activeJwk *Jwk,
+	lastModified time.Time,
+	hasNextKey bool,
+	now time.Time,
+) error {
+	var staleAt time.Time
+	if p.excludeExpired {
+		for _, jwk := range keys {
+			// excluding an expired key changes the published keys as well
+			if jwk.IsExpiredAt(now) && jwk.NotAfter.After(lastModified) {
+				lastModified = jwk.NotAfter
+			}
+		}
+
+		keys = withoutExpired(slices.Clone(keys), now)
+		for _, jwk := range keys {
+			if !jwk.NotAfter.IsZero() && (staleAt.IsZero() || jwk.NotAfter.Before(staleAt)) {
+				staleAt = jwk.NotAfter
+			}
+		}
+	}
+
+	snapshot, err := newSnapshot(keys, activeJwk, lastModified, hasNextKey)
+	if err != nil {
+		return err
+	}
+	snapshot.staleAt = staleAt
+
+	p.snapshot.Store(snapshot)
+	return nil
+}
+
+// current returns the published snapshot. It is only rebuilt by calling republish while holding the mutex of the
+// cache if a key expired that has to be excluded.
+func (p *snapshotPublisher) current(cacheMutex *sync.Mutex, republish func(now time.Time) error) *Snapshot {
+	snapshot := p.snapshot.Load()
+	now := time.Now()
+	if !snapshot.isStaleAt(now) {
+		return snapshot
+	}
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	// the snapshot may have been rebuilt while waiting for the lock
+	if snapshot = p.snapshot.Load(); snapshot.isStaleAt(now) {
+		if err := republish(now); err != nil {
+			log.Error().Msgf("failed to rebuild JWKS snapshot: %v", err)
+			return snapshot
+		}
+		snapshot = p.snapshot.Load()
+	}
+	return snapshot
+}
+
+// jwksDocument is the JSON representation of a JWK set.
+type jwksDocument struct {
+	Keys []*Jwk `json:"keys"`
+}
+
+func newSnapshot(keys []*Jwk, activeJwk *Jwk, lastModified time.Time, hasNextKey bool) (*Snapshot, error) {
+	jsonByteArray, err := json.Marshal(jwksDocument{Keys: keys})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render JWKS: %w", err)
+	}
+
+	gzipByteArray, err := compress(jsonByteArray, func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, gzip.BestCompression)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress JWKS with gzip: %w", err)
+	}
+
+	brotliByteArray, err := compress(jsonByteArray, func(w io.Writer) (io.WriteCloser, error) {
+		return brotli.NewWriterLevel(w, brotli.BestCompression), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress JWKS with brotli: %w", err)
+	}
+
+	return &Snapshot{
+		Keys:         keys,
+		JSON:         jsonByteArray,
+		Gzip:         gzipByteArray,
+		Brotli:       brotliByteArray,
+		ETag:         ETag(jsonByteArray),
+		LastModified: lastModified,
+		HasNextKey:   hasNextKey,
+		activeJwk:    activeJwk,
+	}, nil
+}
+
+// ETag returns the strong ETag of the response body, which is the base64url encoded SHA-256 hash of the body.
+func ETag(body []byte) string {
+	hash := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(hash[:]) + `"`
+}
+
+func compress(data []byte, newWriter func(w io.Writer) (io.WriteCloser, error)) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer, err := newWriter(&buffer)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (s *Snapshot) isStaleAt(t time.Time) bool {
+	return !s.staleAt.IsZero() && t.After(s.staleAt)
+}
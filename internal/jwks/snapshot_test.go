@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newBenchmarkFileProvider(tb testing.TB) *jwks.FileProvider {
+	tb.Helper()
+
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./file_provider_testdata",
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+
+	jwksProvider, err := jwks.NewFileProvider(tb.Context(), jwksConfig)
+	if err != nil {
+		tb.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	return jwksProvider
+}
+
+func TestFileProviderSnapshot(t *testing.T) {
+	jwksProvider := newBenchmarkFileProvider(t)
+
+	snapshot := jwksProvider.Snapshot("default")
+	assert.Same(t, snapshot, jwksProvider.Snapshot("default"), "unchanged keys must not rebuild the snapshot")
+	assert.Equal(t, []string{kidNext, kidCurrent, kidPrevious1}, kidsOf(snapshot.Keys))
+	assert.Equal(t, jwksProvider.LastModified("default"), snapshot.LastModified)
+	assert.True(t, snapshot.HasNextKey)
+	assert.Equal(t, jwks.ETag(snapshot.JSON), snapshot.ETag)
+
+	expectedJSON, err := json.Marshal(map[string][]*jwks.Jwk{"keys": jwksProvider.GetJwks("default")})
+	if err != nil {
+		t.Fatalf("failed to marshal JWKS: %v", err)
+	}
+	assert.JSONEq(t, string(expectedJSON), string(snapshot.JSON))
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(snapshot.Gzip))
+	if err != nil {
+		t.Fatalf("failed to create gzip reader: %v", err)
+	}
+	gzipJSON, err := io.ReadAll(gzipReader)
+	if err != nil {
+		t.Fatalf("failed to decompress gzip: %v", err)
+	}
+	assert.Equal(t, snapshot.JSON, gzipJSON)
+
+	brotliJSON, err := io.ReadAll(brotli.NewReader(bytes.NewReader(snapshot.Brotli)))
+	if err != nil {
+		t.Fatalf("failed to decompress brotli: %v", err)
+	}
+	assert.Equal(t, snapshot.JSON, brotliJSON)
+}
+
+func TestProviderSnapshot(t *testing.T) {
+	type snapshotProvider interface {
+		jwks.Provider
+		jwks.SnapshotProvider
+	}
+
+	tests := []struct {
+		name         string
+		newProvider  func(t *testing.T) (snapshotProvider, error)
+		expectedKids []string
+	}{
+		{
+			name: "directory provider",
+			newProvider: func(t *testing.T) (snapshotProvider, error) {
+				return jwks.NewDirectoryProvider(t.Context(), &config.JwksFileConfig{
+					MountedPath:      directoryTestPath,
+					ActiveMarkerFile: "active",
+				})
+			},
+			expectedKids: []string{kidCurrent, kidNext, kidPrevious1, kidPrevious2},
+		},
+		{
+			name: "kubernetes provider",
+			newProvider: func(t *testing.T) (snapshotProvider, error) {
+				client := fake.NewClientset(
+					newTLSSecret(t, "next-tls", "next-tls.crt", kidNext),
+					newTLSSecret(t, "tls", "tls.crt", kidCurrent),
+				)
+				return jwks.NewKubernetesProvider(t.Context(), client, newKubernetesConfig())
+			},
+			expectedKids: []string{kidNext, kidCurrent},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwksProvider, err := tt.newProvider(t)
+			if err != nil {
+				t.Fatalf("failed to create JWKS provider: %v", err)
+			}
+
+			snapshot := jwksProvider.Snapshot("default")
+			if !assert.NotNil(t, snapshot) {
+				return
+			}
+			assert.Same(t, snapshot, jwksProvider.Snapshot("default"), "unchanged keys must not rebuild the snapshot")
+			assert.Equal(t, tt.expectedKids, kidsOf(snapshot.Keys))
+			assert.Equal(t, jwksProvider.GetJwks("default"), snapshot.Keys)
+			assert.Equal(t, jwksProvider.LastModified("default"), snapshot.LastModified)
+			assert.Equal(t, jwksProvider.HasNextKey("default"), snapshot.HasNextKey)
+			assert.Equal(t, jwks.ETag(snapshot.JSON), snapshot.ETag)
+			assert.NotEmpty(t, snapshot.Gzip)
+			assert.NotEmpty(t, snapshot.Brotli)
+		})
+	}
+}
+
+func BenchmarkFileProviderGetJwks(b *testing.B) {
+	jwksProvider := newBenchmarkFileProvider(b)
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = jwksProvider.GetJwks("default")
+		}
+	})
+}
+
+// BenchmarkJwksResponse compares rendering the JWKS response per request with the pre-rendered snapshot.
+func BenchmarkJwksResponse(b *testing.B) {
+	jwksProvider := newBenchmarkFileProvider(b)
+
+	b.Run("marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := json.Marshal(map[string][]*jwks.Jwk{"keys": jwksProvider.GetJwks("default")}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+
+	b.Run("snapshot", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = jwksProvider.Snapshot("default").JSON
+			}
+		})
+	})
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	expiryCheckerName = "JWKS Expiry Checker"
+
+	// expiryCheckInterval is the interval in seconds in which the expiry of the certificates is checked.
+	expiryCheckInterval = 60
+
+	// expiredThreshold marks a key for which the expiry was already logged.
+	expiredThreshold time.Duration = -1
+)
+
+// expiryMonitor logs a warning once a certificate crosses one of the configured thresholds before its
+// expiry and an error once it is expired. Every threshold is logged only once per key ID.
+type expiryMonitor struct {
+	thresholds []time.Duration
+
+	// warned contains the smallest threshold that was already logged per key ID
+	warned map[string]time.Duration
+
+	mutex *sync.Mutex
+}
+
+func newExpiryMonitor(thresholds []time.Duration) *expiryMonitor {
+	sortedThresholds := slices.Clone(thresholds)
+	slices.Sort(sortedThresholds)
+
+	return &expiryMonitor{
+		thresholds: sortedThresholds,
+		warned:     make(map[string]time.Duration),
+		mutex:      &sync.Mutex{},
+	}
+}
+
+func (m *expiryMonitor) check(keys []*Jwk, now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	kids := make([]string, 0, len(keys))
+	for _, jwk := range keys {
+		kids = append(kids, jwk.Kid)
+		if jwk.NotAfter.IsZero() {
+			continue
+		}
+
+		if now.Before(jwk.NotBefore) {
+			log.Debug().Msgf("certificate with kid %s is not valid before %s", jwk.Kid, jwk.NotBefore)
+		}
+
+		warnedThreshold, warned := m.warned[jwk.Kid]
+		if jwk.IsExpiredAt(now) {
+			if warnedThreshold != expiredThreshold {
+				log.Error().Msgf("certificate with kid %s expired at %s", jwk.Kid, jwk.NotAfter)
+				m.warned[jwk.Kid] = expiredThreshold
+			}
+			continue
+		}
+
+		remaining := jwk.NotAfter.Sub(now)
+		index := slices.IndexFunc(m.thresholds, func(threshold time.Duration) bool { return remaining <= threshold })
+		if index < 0 || (warned && warnedThreshold <= m.thresholds[index]) {
+			continue
+		}
+
+		log.Warn().Msgf("certificate with kid %s expires in %s at %s",
+			jwk.Kid, remaining.Round(time.Second), jwk.NotAfter)
+		m.warned[jwk.Kid] = m.thresholds[index]
+	}
+
+	// forget keys that are not served anymore
+	for kid := range m.warned {
+		if !slices.Contains(kids, kid) {
+			delete(m.warned, kid)
+		}
+	}
+}
+
+// withoutExpired returns the keys whose certificates are not expired at the given time.
+func withoutExpired(keys []*Jwk, now time.Time) []*Jwk {
+	return slices.DeleteFunc(keys, func(jwk *Jwk) bool {
+		return jwk.IsExpiredAt(now)
+	})
+}
+
+// startExpiryCheck checks the expiry of the keys every expiryCheckInterval seconds in the background, so
+// thresholds are also logged if the certificates are not reloaded in the meantime. The check is stopped when
+// ctx is done.
+func startExpiryCheck(ctx context.Context, monitor *expiryMonitor, keys func() []*Jwk) {
+	runScheduler(ctx, expiryCheckerName, expiryCheckInterval, func() {
+		monitor.check(keys(), time.Now())
+	})
+}
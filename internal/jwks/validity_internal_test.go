@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiryMonitor(t *testing.T) {
+	now := time.Now()
+	jwk := &Jwk{Kid: "kid", NotBefore: now.Add(-time.Hour), NotAfter: now.Add(100 * time.Hour)}
+
+	monitor := newExpiryMonitor([]time.Duration{24 * time.Hour, 168 * time.Hour, time.Hour})
+	assert.Equal(t, []time.Duration{time.Hour, 24 * time.Hour, 168 * time.Hour}, monitor.thresholds)
+
+	tests := []struct {
+		name              string
+		now               time.Time
+		expectedThreshold time.Duration
+	}{
+		{
+			name:              "the largest threshold is crossed",
+			now:               now,
+			expectedThreshold: 168 * time.Hour,
+		},
+		{
+			name:              "the threshold is kept until the next one is crossed",
+			now:               now.Add(70 * time.Hour),
+			expectedThreshold: 168 * time.Hour,
+		},
+		{
+			name:              "crossing several thresholds at once records the smallest one",
+			now:               now.Add(99*time.Hour + 30*time.Minute),
+			expectedThreshold: time.Hour,
+		},
+		{
+			name:              "an expired certificate is recorded",
+			now:               now.Add(101 * time.Hour),
+			expectedThreshold: expiredThreshold,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor.check([]*Jwk{jwk}, tt.now)
+			assert.Equal(t, tt.expectedThreshold, monitor.warned[jwk.Kid])
+		})
+	}
+
+	// keys that are not published anymore are forgotten
+	monitor.check(nil, now)
+	assert.Empty(t, monitor.warned)
+}
+
+func TestExpiryMonitorIgnoresValidCertificates(t *testing.T) {
+	now := time.Now()
+	jwk := &Jwk{Kid: "kid", NotBefore: now.Add(-time.Hour), NotAfter: now.Add(1000 * time.Hour)}
+
+	monitor := newExpiryMonitor([]time.Duration{720 * time.Hour})
+	monitor.check([]*Jwk{jwk}, now)
+	assert.Empty(t, monitor.warned)
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks_test
+
+import (
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/testutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func writeCertificate(t *testing.T, dir string, name string, kid string, notBefore time.Time, notAfter time.Time) {
+	t.Helper()
+
+	certificate := testutil.NewCertificate(t, testutil.CertificateOptions{NotBefore: notBefore, NotAfter: notAfter})
+	if err := os.WriteFile(path.Join(dir, name+".crt"), certificate.CertPEM, 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(path.Join(dir, name+".kid"), []byte(kid), 0o600); err != nil {
+		t.Fatalf("failed to write key ID: %v", err)
+	}
+}
+
+func kidsOf(keys []*jwks.Jwk) []string {
+	kids := make([]string, 0, len(keys))
+	for _, jwk := range keys {
+		kids = append(kids, jwk.Kid)
+	}
+	return kids
+}
+
+func TestJwkValidity(t *testing.T) {
+	notBefore := time.Now().Add(-time.Hour).Truncate(time.Second)
+	notAfter := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	dir := t.TempDir()
+	writeCertificate(t, dir, "tls", kidCurrent, notBefore, notAfter)
+
+	jwksConfig := newSlotTestConfig(dir)
+	jwksConfig.UpdateInterval = 0
+	jwksConfig.NextOptional = true
+	jwksConfig.PrevOptional = true
+
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+
+	jwk := jwksProvider.GetJwks("default")[0]
+	assert.True(t, jwk.NotBefore.Equal(notBefore))
+	assert.True(t, jwk.NotAfter.Equal(notAfter))
+	assert.False(t, jwk.IsExpiredAt(notAfter))
+	assert.True(t, jwk.IsExpiredAt(notAfter.Add(time.Second)))
+}
+
+func TestFileProviderExpiredCertificates(t *testing.T) {
+	tests := []struct {
+		name           string
+		excludeExpired bool
+		expectedKids   []string
+	}{
+		{
+			name:         "expired certificates are published by default",
+			expectedKids: []string{kidNext, kidCurrent, kidPrevious1},
+		},
+		{
+			name:           "expired certificates are excluded if configured",
+			excludeExpired: true,
+			expectedKids:   []string{kidNext},
+		},
+	}
+
+	now := time.Now()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// the next certificate is not valid yet, but it is published ahead of the rotation
+			writeCertificate(t, dir, "next-tls", kidNext, now.Add(time.Hour), now.Add(48*time.Hour))
+			writeCertificate(t, dir, "tls", kidCurrent, now.Add(-48*time.Hour), now.Add(-time.Hour))
+			writeCertificate(t, dir, "prev-tls", kidPrevious1, now.Add(-96*time.Hour), now.Add(-48*time.Hour))
+
+			jwksConfig := newSlotTestConfig(dir)
+			jwksConfig.UpdateInterval = 0
+			jwksConfig.ExcludeExpired = tt.excludeExpired
+
+			jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+			if err != nil {
+				t.Fatalf("failed to create JWKS file provider: %v", err)
+			}
+
+			assert.Equal(t, tt.expectedKids, kidsOf(jwksProvider.GetJwks("default")))
+			assert.Nil(t, jwksProvider.GetDefaultRealm("default"))
+		})
+	}
+}
+
+func TestFileProviderCertificateExpiresAtRuntime(t *testing.T) {
+	now := time.Now()
+
+	dir := t.TempDir()
+	writeCertificate(t, dir, "next-tls", kidNext, now.Add(-time.Hour), now.Add(48*time.Hour))
+	writeCertificate(t, dir, "tls", kidCurrent, now.Add(-time.Hour), now.Add(2*time.Second))
+
+	jwksConfig := newSlotTestConfig(dir)
+	jwksConfig.UpdateInterval = 0
+	jwksConfig.PrevOptional = true
+	jwksConfig.ExcludeExpired = true
+
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	assert.Equal(t, []string{kidNext, kidCurrent}, kidsOf(jwksProvider.GetJwks("default")))
+	assert.NotNil(t, jwksProvider.GetDefaultRealm("default"))
+
+	// the certificate expires without being reloaded
+	assert.Eventually(t, func() bool {
+		return jwksProvider.GetDefaultRealm("default") == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, []string{kidNext}, kidsOf(jwksProvider.GetJwks("default")))
+}
+
+func TestDirectoryProviderExpiredCertificates(t *testing.T) {
+	now := time.Now()
+
+	dir := t.TempDir()
+	writeCertificate(t, dir, "current", kidCurrent, now.Add(-time.Hour), now.Add(time.Hour))
+	writeCertificate(t, dir, "previous-1", kidPrevious1, now.Add(-48*time.Hour), now.Add(-time.Hour))
+
+	jwksConfig := &config.JwksFileConfig{
+		MountedPath:    dir,
+		ActiveKid:      kidCurrent,
+		ExcludeExpired: true,
+	}
+
+	jwksProvider, err := jwks.NewDirectoryProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS directory provider: %v", err)
+	}
+
+	assert.Equal(t, []string{kidCurrent}, kidsOf(jwksProvider.GetJwks("default")))
+	assert.NotNil(t, jwksProvider.GetDefaultRealm("default"))
+}
+
+func TestKubernetesProviderExpiredActiveCertificate(t *testing.T) {
+	now := time.Now()
+
+	secret := newTLSSecret(t, "tls", "tls.crt", kidCurrent)
+	secret.Data["tls.crt"] = testutil.NewCertificate(t, testutil.CertificateOptions{
+		NotBefore: now.Add(-48 * time.Hour),
+		NotAfter:  now.Add(-time.Hour),
+	}).CertPEM
+
+	k8sConfig := newKubernetesConfig()
+	k8sConfig.ExcludeExpired = true
+
+	jwksProvider, err := jwks.NewKubernetesProvider(t.Context(), fake.NewClientset(secret), k8sConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS kubernetes provider: %v", err)
+	}
+
+	assert.Empty(t, jwksProvider.GetJwks("default"))
+	assert.Nil(t, jwksProvider.GetDefaultRealm("default"))
+}
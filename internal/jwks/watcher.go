@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// runWatcher executes the task in the background whenever the content of one of the directories changes, until
+// ctx is done.
+// Bursts of changes are debounced, so the task is executed once no further change was reported for the
+// debounce duration.
+//
+// The directories are watched instead of the files, because Kubernetes updates mounted volumes by
+// atomically swapping the '..data' symlink, which is not reported on the files themselves.
+func runWatcher(ctx context.Context, name string, dirs []string, debounce time.Duration, task func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	for _, dir := range dirs {
+		if err = watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return fmt.Errorf("failed to watch directory %s: %w", dir, err)
+		}
+	}
+
+	log.Info().Msgf("starting %s ...", name)
+	go func() {
+		var debounceTimer *time.Timer
+		defer func() {
+			if debounceTimer != nil {
+				debounceTimer.Stop()
+			}
+			_ = watcher.Close()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info().Msgf("%s stopped", name)
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+
+				log.Debug().Msgf("%s received event %s", name, event)
+				if debounceTimer == nil {
+					debounceTimer = time.AfterFunc(debounce, task)
+				} else {
+					debounceTimer.Reset(debounce)
+				}
+			case watchErr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error().Msgf("%s failed: %v", name, watchErr)
+			}
+		}
+	}()
+
+	log.Info().Msgf("%s started", name)
+	return nil
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// jwksSource identifies a JWKS provider. The realm is empty for a provider serving every realm.
+type jwksSource struct {
+	provider string
+	realm    string
+}
+
+// jwksCollector exposes the state of the JWKS providers. Durations are calculated when the metrics are
+// collected, so they are accurate even if the keys were not reloaded for a long time.
+type jwksCollector struct {
+	lastSuccess map[jwksSource]time.Time
+	keysPerSlot map[jwksSource]map[string]int
+	expiries    map[jwksSource]map[string]time.Time
+
+	mutex *sync.Mutex
+
+	lastSuccessDesc *prometheus.Desc
+	keysDesc        *prometheus.Desc
+	expiryDesc      *prometheus.Desc
+}
+
+func newJwksCollector() *jwksCollector {
+	return &jwksCollector{
+		lastSuccess: make(map[jwksSource]time.Time),
+		keysPerSlot: make(map[jwksSource]map[string]int),
+		expiries:    make(map[jwksSource]map[string]time.Time),
+		mutex:       &sync.Mutex{},
+		lastSuccessDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "jwks", "seconds_since_last_successful_reload"),
+			"Seconds since the last successful JWKS reload by provider and realm.",
+			[]string{"provider", "realm"}, nil,
+		),
+		keysDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "jwks", "keys"),
+			"Number of published keys by provider, realm and slot.",
+			[]string{"provider", "realm", "slot"}, nil,
+		),
+		expiryDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "jwks", "certificate_expiry_seconds"),
+			"Seconds until the certificate of a published key expires, negative if it is expired.",
+			[]string{"provider", "realm", "kid"}, nil,
+		),
+	}
+}
+
+func (c *jwksCollector) setLastSuccess(source jwksSource, t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.lastSuccess[source] = t
+}
+
+func (c *jwksCollector) setKeys(source jwksSource, keysPerSlot map[string]int, expiries map[string]time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.keysPerSlot[source] = maps.Clone(keysPerSlot)
+	c.expiries[source] = maps.Clone(expiries)
+}
+
+func (c *jwksCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.lastSuccessDesc
+	ch <- c.keysDesc
+	ch <- c.expiryDesc
+}
+
+func (c *jwksCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for source, lastSuccess := range c.lastSuccess {
+		ch <- prometheus.MustNewConstMetric(
+			c.lastSuccessDesc, prometheus.GaugeValue, now.Sub(lastSuccess).Seconds(), source.provider, source.realm,
+		)
+	}
+
+	for source, keysPerSlot := range c.keysPerSlot {
+		for slot, count := range keysPerSlot {
+			ch <- prometheus.MustNewConstMetric(
+				c.keysDesc, prometheus.GaugeValue, float64(count), source.provider, source.realm, slot,
+			)
+		}
+	}
+
+	for source, expiries := range c.expiries {
+		for kid, notAfter := range expiries {
+			ch <- prometheus.MustNewConstMetric(
+				c.expiryDesc, prometheus.GaugeValue, notAfter.Sub(now).Seconds(), source.provider, source.realm, kid,
+			)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "issuer_service"
+
+	ReloadResultSuccess = "success"
+	ReloadResultFailure = "failure"
+)
+
+//nolint:gochecknoglobals // metrics are registered once in the default registry
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route, realm, method and status code.",
+	}, []string{"route", "realm", "method", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, realm and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "realm", "method"})
+
+	reloadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jwks_reloads_total",
+		Help:      "Number of JWKS reloads by provider, realm and result.",
+	}, []string{"provider", "realm", "result"})
+
+	jwksState = newJwksCollector()
+)
+
+func init() {
+	prometheus.MustRegister(jwksState)
+}
+
+// Handler returns the HTTP handler serving the metrics of the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveRequest records a finished HTTP request.
+func ObserveRequest(route string, realm string, method string, status int, duration time.Duration) {
+	requestsTotal.WithLabelValues(route, realm, method, strconv.Itoa(status)).Inc()
+	requestDuration.WithLabelValues(route, realm, method).Observe(duration.Seconds())
+}
+
+// RecordReload records the result of a JWKS reload of the given provider. The realm is empty for a provider
+// serving every realm.
+func RecordReload(provider string, realm string, err error) {
+	if err != nil {
+		reloadsTotal.WithLabelValues(provider, realm, ReloadResultFailure).Inc()
+		return
+	}
+
+	reloadsTotal.WithLabelValues(provider, realm, ReloadResultSuccess).Inc()
+	jwksState.setLastSuccess(jwksSource{provider: provider, realm: realm}, time.Now())
+}
+
+// SetKeys replaces the published keys of the given provider and realm. keysPerSlot contains the number of keys
+// per slot and expiries the end of the validity period of the certificate per key ID.
+func SetKeys(provider string, realm string, keysPerSlot map[string]int, expiries map[string]time.Time) {
+	jwksState.setKeys(jwksSource{provider: provider, realm: realm}, keysPerSlot, expiries)
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics_test
+
+import (
+	"errors"
+	"io"
+	"issuer-service-go/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// metricValue returns the value of the metric with the given name and labels from the default registry.
+func metricValue(t *testing.T, name string, labels map[string]string) (float64, bool) {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if hasLabels(metric, labels) {
+				return value(metric), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func hasLabels(metric *dto.Metric, labels map[string]string) bool {
+	matches := 0
+	for _, label := range metric.GetLabel() {
+		if expected, exists := labels[label.GetName()]; exists {
+			if expected != label.GetValue() {
+				return false
+			}
+			matches++
+		}
+	}
+	return matches == len(labels)
+}
+
+func value(metric *dto.Metric) float64 {
+	switch {
+	case metric.GetCounter() != nil:
+		return metric.GetCounter().GetValue()
+	case metric.GetGauge() != nil:
+		return metric.GetGauge().GetValue()
+	case metric.GetHistogram() != nil:
+		return float64(metric.GetHistogram().GetSampleCount())
+	}
+	return 0
+}
+
+func TestRecordReload(t *testing.T) {
+	provider := "test-reload"
+
+	metrics.RecordReload(provider, "", nil)
+	metrics.RecordReload(provider, "", nil)
+	metrics.RecordReload(provider, "", errors.New("failed"))
+	metrics.RecordReload(provider, "realm-a", errors.New("failed"))
+
+	successes, _ := metricValue(t, "issuer_service_jwks_reloads_total",
+		map[string]string{"provider": provider, "realm": "", "result": metrics.ReloadResultSuccess})
+	assert.InDelta(t, 2, successes, 0)
+
+	failures, _ := metricValue(t, "issuer_service_jwks_reloads_total",
+		map[string]string{"provider": provider, "realm": "", "result": metrics.ReloadResultFailure})
+	assert.InDelta(t, 1, failures, 0)
+
+	failures, _ = metricValue(t, "issuer_service_jwks_reloads_total",
+		map[string]string{"provider": provider, "realm": "realm-a", "result": metrics.ReloadResultFailure})
+	assert.InDelta(t, 1, failures, 0)
+
+	sinceLastSuccess, exists := metricValue(t, "issuer_service_jwks_seconds_since_last_successful_reload",
+		map[string]string{"provider": provider, "realm": ""})
+	assert.True(t, exists)
+	assert.Less(t, sinceLastSuccess, 5.0)
+
+	_, exists = metricValue(t, "issuer_service_jwks_seconds_since_last_successful_reload",
+		map[string]string{"provider": provider, "realm": "realm-a"})
+	assert.False(t, exists)
+}
+
+func TestSetKeys(t *testing.T) {
+	provider := "test-keys"
+
+	metrics.SetKeys(provider, "",
+		map[string]int{"active": 1, "next": 0},
+		map[string]time.Time{"kid-1": time.Now().Add(time.Hour), "kid-2": time.Now().Add(-time.Hour)},
+	)
+
+	activeKeys, _ := metricValue(t, "issuer_service_jwks_keys", map[string]string{"provider": provider, "slot": "active"})
+	assert.InDelta(t, 1, activeKeys, 0)
+
+	nextKeys, exists := metricValue(t, "issuer_service_jwks_keys", map[string]string{"provider": provider, "slot": "next"})
+	assert.True(t, exists)
+	assert.InDelta(t, 0, nextKeys, 0)
+
+	expiry, _ := metricValue(t, "issuer_service_jwks_certificate_expiry_seconds",
+		map[string]string{"provider": provider, "kid": "kid-1"})
+	assert.InDelta(t, time.Hour.Seconds(), expiry, 5)
+
+	expiry, _ = metricValue(t, "issuer_service_jwks_certificate_expiry_seconds",
+		map[string]string{"provider": provider, "kid": "kid-2"})
+	assert.InDelta(t, -time.Hour.Seconds(), expiry, 5)
+
+	// keys that are not published anymore are removed
+	metrics.SetKeys(provider, "", map[string]int{"active": 1}, map[string]time.Time{"kid-1": time.Now().Add(time.Hour)})
+
+	_, exists = metricValue(t, "issuer_service_jwks_certificate_expiry_seconds",
+		map[string]string{"provider": provider, "kid": "kid-2"})
+	assert.False(t, exists)
+}
+
+func TestObserveRequest(t *testing.T) {
+	metrics.ObserveRequest("/test/:realm", "default", http.MethodGet, http.StatusOK, 10*time.Millisecond)
+
+	requests, _ := metricValue(t, "issuer_service_http_requests_total",
+		map[string]string{"route": "/test/:realm", "realm": "default", "method": http.MethodGet, "status": "200"})
+	assert.InDelta(t, 1, requests, 0)
+
+	observations, _ := metricValue(t, "issuer_service_http_request_duration_seconds",
+		map[string]string{"route": "/test/:realm", "realm": "default", "method": http.MethodGet})
+	assert.InDelta(t, 1, observations, 0)
+}
+
+func TestHandler(t *testing.T) {
+	metrics.RecordReload("test-handler", "", nil)
+
+	resp := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	assert.Contains(t, string(body),
+		`issuer_service_jwks_reloads_total{provider="test-handler",realm="",result="success"} 1`)
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package realm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"issuer-service-go/internal/config"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Wildcard accepts every realm.
+	Wildcard = "*"
+	// DefaultRealm is the only realm served if no realm is configured.
+	DefaultRealm = "default"
+)
+
+// Registry contains the realms served by the issuer service.
+type Registry struct {
+	realms   []string
+	wildcard bool
+}
+
+// NewRegistry creates the registry from the realms of REALMS and REALMS_FILE. If neither configures a realm, only
+// DefaultRealm is served. The realms of providerRealms, which have keys of their own, are served in any case.
+// Every realm is only accepted if one of the realms is the wildcard.
+func NewRegistry(realmConfig *config.RealmConfig, providerRealms []string) (*Registry, error) {
+	realms := trimRealms(realmConfig.Realms)
+	if realmConfig.RealmsFile != "" {
+		fileRealms, err := readRealmsFile(realmConfig.RealmsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read realms file: %w", err)
+		}
+		realms = append(realms, fileRealms...)
+	}
+
+	if len(realms) == 0 {
+		log.Info().Msgf("no realms are configured, only realm %s is served", DefaultRealm)
+		realms = append(realms, DefaultRealm)
+	}
+	realms = append(realms, trimRealms(providerRealms)...)
+
+	slices.Sort(realms)
+	registry := &Registry{
+		realms:   slices.Compact(realms),
+		wildcard: slices.Contains(realms, Wildcard),
+	}
+	if registry.wildcard {
+		log.Warn().Msg("the wildcard realm is configured, every realm is accepted")
+	}
+	return registry, nil
+}
+
+// NewDefaultRegistry creates a registry that only serves DefaultRealm, like NewRegistry does if no realm is
+// configured.
+func NewDefaultRegistry() *Registry {
+	return &Registry{realms: []string{DefaultRealm}}
+}
+
+// NewWildcardRegistry creates a registry that accepts every realm.
+func NewWildcardRegistry() *Registry {
+	return &Registry{realms: []string{Wildcard}, wildcard: true}
+}
+
+// Exists returns whether the realm is served.
+func (r *Registry) Exists(realm string) bool {
+	return r.wildcard || r.Contains(realm)
+}
+
+// Contains returns whether the realm is configured explicitly. Unlike Exists, it does not accept every realm if the
+// wildcard is configured.
+func (r *Registry) Contains(realm string) bool {
+	_, found := slices.BinarySearch(r.realms, realm)
+	return found && realm != Wildcard
+}
+
+// IsWildcard returns whether every realm is accepted.
+func (r *Registry) IsWildcard() bool {
+	return r.wildcard
+}
+
+// Realms returns the sorted names of the configured realms.
+func (r *Registry) Realms() []string {
+	return slices.Clone(r.realms)
+}
+
+// trimRealms returns the realms without surrounding whitespace and without empty ones.
+func trimRealms(names []string) []string {
+	realms := make([]string, 0, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			realms = append(realms, name)
+		}
+	}
+	return realms
+}
+
+// readRealmsFile reads one realm per line. Empty lines and lines starting with '#' are ignored.
+func readRealmsFile(file string) ([]string, error) {
+	fileByteArray, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var realms []string
+	scanner := bufio.NewScanner(bytes.NewReader(fileByteArray))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		realms = append(realms, line)
+	}
+	return realms, scanner.Err()
+}
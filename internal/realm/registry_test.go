@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package realm_test
+
+import (
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/realm"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRegistry(t *testing.T) {
+	tests := []struct {
+		name           string
+		realmConfig    config.RealmConfig
+		providerRealms []string
+		err            bool
+		wildcard       bool
+		expectedRealms []string
+	}{
+		{
+			name:           "only the default realm is served if no realm is configured",
+			realmConfig:    config.RealmConfig{},
+			expectedRealms: []string{"default"},
+		},
+		{
+			name:           "realms from the environment",
+			realmConfig:    config.RealmConfig{Realms: []string{"default", " other-realm ", "default", ""}},
+			expectedRealms: []string{"default", "other-realm"},
+		},
+		{
+			name: "realms from the environment and the file",
+			realmConfig: config.RealmConfig{
+				Realms:     []string{"env-realm"},
+				RealmsFile: "./registry_testdata/realms",
+			},
+			expectedRealms: []string{"default", "env-realm", "other-realm", "spaces"},
+		},
+		{
+			name:           "wildcard",
+			realmConfig:    config.RealmConfig{Realms: []string{"default", "*"}},
+			wildcard:       true,
+			expectedRealms: []string{"*", "default"},
+		},
+		{
+			name:           "realms with keys of their own are served in addition to the default realm",
+			realmConfig:    config.RealmConfig{},
+			providerRealms: []string{"", "realm-b", "realm-a"},
+			expectedRealms: []string{"default", "realm-a", "realm-b"},
+		},
+		{
+			name:           "realms with keys of their own are served in addition to the configured realms",
+			realmConfig:    config.RealmConfig{Realms: []string{"other-realm", "realm-a"}},
+			providerRealms: []string{"realm-a", "realm-b"},
+			expectedRealms: []string{"other-realm", "realm-a", "realm-b"},
+		},
+		{
+			name:        "error if the file does not exist",
+			realmConfig: config.RealmConfig{RealmsFile: "./registry_testdata/missing"},
+			err:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := realm.NewRegistry(&tt.realmConfig, tt.providerRealms)
+			assert.Equalf(t, tt.err, err != nil, "expected error: %v, got: %v", tt.err, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.wildcard, registry.IsWildcard())
+			assert.Equal(t, tt.expectedRealms, registry.Realms())
+		})
+	}
+}
+
+func TestRegistryExists(t *testing.T) {
+	registry, err := realm.NewRegistry(&config.RealmConfig{Realms: []string{"default", "other-realm"}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create realm registry: %v", err)
+	}
+
+	assert.True(t, registry.Exists("default"))
+	assert.True(t, registry.Exists("other-realm"))
+	assert.False(t, registry.Exists("typo"))
+	assert.False(t, registry.Exists(""))
+	assert.False(t, registry.Exists("*"))
+
+	assert.True(t, realm.NewWildcardRegistry().Exists("typo"))
+
+	defaultRegistry, err := realm.NewRegistry(&config.RealmConfig{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create realm registry: %v", err)
+	}
+	assert.True(t, defaultRegistry.Exists("default"))
+	assert.False(t, defaultRegistry.Exists("typo"), "every realm is only accepted with the wildcard")
+	assert.Equal(t, defaultRegistry.Realms(), realm.NewDefaultRegistry().Realms())
+}
+
+func TestRegistryContains(t *testing.T) {
+	registry, err := realm.NewRegistry(&config.RealmConfig{Realms: []string{"default", "*"}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create realm registry: %v", err)
+	}
+
+	assert.True(t, registry.Exists("typo"))
+	assert.True(t, registry.Contains("default"))
+	assert.False(t, registry.Contains("typo"), "the wildcard accepts the realm, but does not configure it")
+	assert.False(t, registry.Contains("*"))
+}
@@ -0,0 +1,216 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"issuer-service-go/internal/jwks"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/rs/zerolog/log"
+)
+
+// AdminHandler serves the admin API, which shows the key inventory and reloads the keys on demand, so rotations
+// can be debugged without reading the logs of the pod.
+type AdminHandler struct {
+	jwksProvider jwks.Provider
+	tokenHash    *[sha256.Size]byte
+}
+
+// AdminKeysResponse is the key inventory of every realm with keys of its own.
+type AdminKeysResponse struct {
+	Realms []AdminRealmKeys `json:"realms"`
+}
+
+// AdminRealmKeys contains the loaded keys of a realm. The realm is empty for the keys of all realms without
+// keys of their own.
+type AdminRealmKeys struct {
+	Realm string     `json:"realm"`
+	Keys  []AdminKey `json:"keys"`
+}
+
+// AdminKey describes a loaded key and the certificate it was loaded from.
+type AdminKey struct {
+	Kid               string    `json:"kid"`
+	Slot              string    `json:"slot,omitempty"`
+	Alg               string    `json:"alg"`
+	Kty               string    `json:"kty"`
+	SHA1Fingerprint   string    `json:"sha1Fingerprint"`
+	SHA256Fingerprint string    `json:"sha256Fingerprint"`
+	NotBefore         time.Time `json:"notBefore"`
+	NotAfter          time.Time `json:"notAfter"`
+	Expired           bool      `json:"expired"`
+	Source            string    `json:"source,omitempty"`
+	LoadedAt          time.Time `json:"loadedAt"`
+}
+
+// AdminReloadResponse contains the result of the reload of every realm with keys of its own.
+type AdminReloadResponse struct {
+	Results []AdminReloadResult `json:"results"`
+}
+
+// AdminReloadResult is the result of the reload of the keys of a realm with the load status of every slot.
+type AdminReloadResult struct {
+	Realm string            `json:"realm"`
+	Slots []jwks.SlotStatus `json:"slots,omitempty"`
+	Error string            `json:"error,omitempty"`
+}
+
+// NewAdminHandler creates the handler of the admin API. If tokenFile is set, every request has to send its
+// content as bearer token.
+func NewAdminHandler(jwksProvider jwks.Provider, tokenFile string) (*AdminHandler, error) {
+	ah := &AdminHandler{jwksProvider: jwksProvider}
+	if tokenFile == "" {
+		log.Warn().Msg("ADMIN_TOKEN_FILE is not set, the admin API is not authenticated")
+		return ah, nil
+	}
+
+	tokenByteArray, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin token: %w", err)
+	}
+	token := strings.TrimSpace(string(tokenByteArray))
+	if token == "" {
+		return nil, fmt.Errorf("admin token file %s is empty", tokenFile)
+	}
+	tokenHash := sha256.Sum256([]byte(token))
+	ah.tokenHash = &tokenHash
+	return ah, nil
+}
+
+// NewAdminServer creates the server of the admin API, which listens separately from the public endpoints.
+func NewAdminServer(handler *AdminHandler) *FiberServer {
+	server := &FiberServer{
+		App: fiber.New(fiber.Config{
+			ServerHeader: "issuer-service",
+			AppName:      "issuer-service-admin",
+		}),
+	}
+
+	server.App.Use(recover.New())
+	admin := server.App.Group("/admin", handler.authenticate)
+	admin.Get("/keys", handler.KeysHandler)
+	admin.Post("/reload", handler.ReloadHandler)
+
+	return server
+}
+
+// KeysHandler responds with the loaded keys of every realm with keys of its own, or of the realm given by the
+// 'realm' query parameter. Expired keys are included, even if they are not published.
+func (ah *AdminHandler) KeysHandler(c *fiber.Ctx) error {
+	now := time.Now()
+
+	response := AdminKeysResponse{Realms: []AdminRealmKeys{}}
+	for _, realm := range ah.realms(c) {
+		realmKeys := AdminRealmKeys{Realm: realm, Keys: []AdminKey{}}
+		for _, jwk := range jwks.LoadedJwks(ah.jwksProvider, realm) {
+			realmKeys.Keys = append(realmKeys.Keys, newAdminKey(jwk, now))
+		}
+		response.Realms = append(response.Realms, realmKeys)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// ReloadHandler reloads the keys of every realm with keys of its own, or of the realm given by the 'realm' query
+// parameter, and responds with the load status of every slot. It responds with 500 if any reload failed and
+// with 501 if the provider cannot reload on demand.
+func (ah *AdminHandler) ReloadHandler(c *fiber.Ctx) error {
+	reloader, ok := ah.jwksProvider.(jwks.Reloader)
+	if !ok {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Error{
+			Code:    fiber.StatusNotImplemented,
+			Message: jwks.ErrReloadNotSupported.Error(),
+		})
+	}
+
+	status := fiber.StatusOK
+	response := AdminReloadResponse{Results: []AdminReloadResult{}}
+	for _, realm := range ah.realms(c) {
+		log.Info().Msgf("reloading the keys of realm '%s' on demand", realm)
+		slots, err := reloader.Reload(realm)
+
+		result := AdminReloadResult{Realm: realm, Slots: slots}
+		switch {
+		case errors.Is(err, jwks.ErrReloadNotSupported):
+			result.Error = err.Error()
+			status = max(status, fiber.StatusNotImplemented)
+		case err != nil:
+			log.Error().Msgf("failed to reload the keys of realm '%s': %v", realm, err)
+			result.Error = err.Error()
+			status = max(status, fiber.StatusInternalServerError)
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(status).JSON(response)
+}
+
+// authenticate rejects requests without the configured bearer token. The scheme is case-insensitive (RFC 9110
+// section 11.1).
+func (ah *AdminHandler) authenticate(c *fiber.Ctx) error {
+	if ah.tokenHash == nil {
+		return c.Next()
+	}
+
+	scheme, token, _ := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	tokenHash := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	if !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare(tokenHash[:], ah.tokenHash[:]) != 1 {
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="admin"`)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Error{
+			Code:    fiber.StatusUnauthorized,
+			Message: "invalid admin token",
+		})
+	}
+	return c.Next()
+}
+
+// realms returns the realm of the 'realm' query parameter, or every realm with keys of its own.
+func (ah *AdminHandler) realms(c *fiber.Ctx) []string {
+	if realm := c.Query("realm"); realm != "" {
+		return []string{realm}
+	}
+	return jwks.ProviderRealms(ah.jwksProvider)
+}
+
+func newAdminKey(jwk *jwks.Jwk, now time.Time) AdminKey {
+	return AdminKey{
+		Kid:               jwk.Kid,
+		Slot:              jwk.Slot,
+		Alg:               jwk.Alg,
+		Kty:               jwk.Kty,
+		SHA1Fingerprint:   fingerprint(jwk.X5t),
+		SHA256Fingerprint: fingerprint(jwk.X5tS256),
+		NotBefore:         jwk.NotBefore,
+		NotAfter:          jwk.NotAfter,
+		Expired:           jwk.IsExpiredAt(now),
+		Source:            jwk.Source,
+		LoadedAt:          jwk.LoadedAt,
+	}
+}
+
+// fingerprint returns the base64url encoded thumbprint in the colon-separated hex format of openssl.
+func fingerprint(thumbprint string) string {
+	digest, err := base64.RawURLEncoding.DecodeString(thumbprint)
+	if err != nil {
+		return ""
+	}
+
+	hexBytes := make([]string, len(digest))
+	for i, b := range digest {
+		hexBytes[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(hexBytes, ":")
+}
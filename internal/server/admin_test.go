@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"encoding/json"
+	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/server"
+	"issuer-service-go/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	adminTestToken = "4d6f6e6b6579"
+)
+
+// newAdminTestServer creates the admin server of the provider, which requires adminTestToken.
+func newAdminTestServer(t *testing.T, jwksProvider jwks.Provider) *server.FiberServer {
+	t.Helper()
+
+	tokenFile := path.Join(t.TempDir(), "admin-token")
+	writeTestFile(t, tokenFile, []byte(adminTestToken+"\n"))
+
+	adminHandler, err := server.NewAdminHandler(jwksProvider, tokenFile)
+	if err != nil {
+		t.Fatalf("failed to create admin handler: %v", err)
+	}
+	return server.NewAdminServer(adminHandler)
+}
+
+// sendAdminRequest sends the request with adminTestToken and decodes the response body into v.
+func sendAdminRequest(t *testing.T, srv *server.FiberServer, method string, route string, v any) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, route, nil)
+	req.Header.Set("Authorization", "Bearer "+adminTestToken)
+	resp, err := srv.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestNewAdminHandler(t *testing.T) {
+	emptyTokenFile := path.Join(t.TempDir(), "admin-token")
+	writeTestFile(t, emptyTokenFile, []byte("\n"))
+
+	_, err := server.NewAdminHandler(nil, "")
+	assert.NoError(t, err, "the token is optional")
+
+	_, err = server.NewAdminHandler(nil, emptyTokenFile)
+	assert.Error(t, err)
+
+	_, err = server.NewAdminHandler(nil, path.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestAdminAuthentication(t *testing.T) {
+	srv := newAdminTestServer(t, &healthTestProvider{})
+
+	tests := []struct {
+		description   string
+		authorization string
+		expectedCode  int
+	}{
+		{description: "valid token", authorization: "Bearer " + adminTestToken, expectedCode: http.StatusOK},
+		{description: "case-insensitive scheme", authorization: "bearer " + adminTestToken, expectedCode: http.StatusOK},
+		{description: "invalid token", authorization: "Bearer invalid", expectedCode: http.StatusUnauthorized},
+		{description: "other scheme", authorization: "Basic " + adminTestToken, expectedCode: http.StatusUnauthorized},
+		{description: "no token", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			resp, err := srv.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestAdminKeys(t *testing.T) {
+	dir := t.TempDir()
+	certificate := testutil.NewCertificate(t, testutil.CertificateOptions{})
+	srv := newAdminTestServer(t, newFileTestProvider(t, dir, certificate))
+
+	var response server.AdminKeysResponse
+	code := sendAdminRequest(t, srv, http.MethodGet, "/admin/keys", &response)
+	assert.Equal(t, http.StatusOK, code)
+	if !assert.Len(t, response.Realms, 1) || !assert.Len(t, response.Realms[0].Keys, 1) {
+		return
+	}
+
+	key := response.Realms[0].Keys[0]
+	assert.Equal(t, validationTestKid, key.Kid)
+	assert.Equal(t, "active", key.Slot)
+	assert.Equal(t, "ES256", key.Alg)
+	assert.Equal(t, path.Join(dir, "tls.crt"), key.Source)
+	assert.Len(t, key.SHA1Fingerprint, 20*3-1)
+	assert.Len(t, key.SHA256Fingerprint, 32*3-1)
+	assert.True(t, key.NotAfter.Equal(certificate.Cert.NotAfter))
+	assert.False(t, key.Expired)
+	assert.False(t, key.LoadedAt.IsZero())
+}
+
+func TestAdminKeysIncludeExpiredKeys(t *testing.T) {
+	expiredJwk := &jwks.Jwk{Kid: "expired", Slot: "active", NotAfter: time.Now().Add(-time.Hour)}
+	srv := newAdminTestServer(t, &healthTestProvider{keys: []*jwks.Jwk{expiredJwk}, excludeExpired: true})
+
+	var response server.AdminKeysResponse
+	code := sendAdminRequest(t, srv, http.MethodGet, "/admin/keys", &response)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, response.Realms, 1) && assert.Len(t, response.Realms[0].Keys, 1) {
+		assert.Equal(t, "expired", response.Realms[0].Keys[0].Kid)
+		assert.True(t, response.Realms[0].Keys[0].Expired)
+	}
+}
+
+func TestAdminReload(t *testing.T) {
+	dir := t.TempDir()
+	certificate := testutil.NewCertificate(t, testutil.CertificateOptions{})
+	srv := newAdminTestServer(t, newFileTestProvider(t, dir, certificate))
+
+	var response server.AdminReloadResponse
+	code := sendAdminRequest(t, srv, http.MethodPost, "/admin/reload", &response)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, response.Results, 1) {
+		assert.Empty(t, response.Results[0].Error)
+		assert.Len(t, response.Results[0].Slots, 3)
+		assert.True(t, response.Results[0].Slots[1].Loaded)
+	}
+
+	// a broken active certificate is reported, while the last loaded key is retained
+	writeTestFile(t, path.Join(dir, "tls.crt"), []byte("-----BEGIN CERT"))
+
+	response = server.AdminReloadResponse{}
+	code = sendAdminRequest(t, srv, http.MethodPost, "/admin/reload?realm=default", &response)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	if assert.Len(t, response.Results, 1) {
+		assert.Equal(t, "default", response.Results[0].Realm)
+		assert.NotEmpty(t, response.Results[0].Error)
+		activeStatus := response.Results[0].Slots[1]
+		assert.False(t, activeStatus.Loaded)
+		assert.True(t, activeStatus.Retained)
+		assert.Equal(t, validationTestKid, activeStatus.Kid)
+	}
+}
+
+func TestAdminReloadNotSupported(t *testing.T) {
+	srv := newAdminTestServer(t, &healthTestProvider{})
+
+	var response map[string]any
+	code := sendAdminRequest(t, srv, http.MethodPost, "/admin/reload", &response)
+	assert.Equal(t, http.StatusNotImplemented, code)
+}
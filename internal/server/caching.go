@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// sendCacheable sends the JSON encoded response with caching headers. If the client already has the current
+// version of the response, '304 Not Modified' is sent without body.
+//
+// The ETag is a hash of the response, Last-Modified is the last change of the published keys and max-age is
+// shortened while a next key is published.
+func sendCacheable(c *fiber.Ctx, response any, lastModified time.Time, hasNextKey bool) error {
+	body, err := c.App().Config().JSONEncoder(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	return sendBody(c, body, fiber.MIMEApplicationJSON, jwks.ETag(body), lastModified, hasNextKey)
+}
+
+// sendSnapshot sends the pre-rendered JWKS response of the snapshot with caching headers. The compressed
+// variants are sent if the client accepts them, each with its own ETag.
+func sendSnapshot(c *fiber.Ctx, snapshot *jwks.Snapshot) error {
+	c.Vary(fiber.HeaderAcceptEncoding)
+
+	body, etag := snapshot.JSON, snapshot.ETag
+	encoding, acceptable := negotiateEncoding(c.Get(fiber.HeaderAcceptEncoding))
+	if !acceptable {
+		return c.Status(fiber.StatusNotAcceptable).JSON(fiber.Error{
+			Code:    fiber.StatusNotAcceptable,
+			Message: "none of the accepted content codings is supported",
+		})
+	}
+	switch encoding {
+	case encodingBrotli:
+		body = snapshot.Brotli
+	case encodingGzip:
+		body = snapshot.Gzip
+	}
+	if encoding != "" {
+		c.Set(fiber.HeaderContentEncoding, encoding)
+		etag = strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+	}
+
+	return sendBody(c, body, fiber.MIMEApplicationJSON, etag, snapshot.LastModified, snapshot.HasNextKey)
+}
+
+// negotiateEncoding returns the supported content coding with the highest q-value of the Accept-Encoding header,
+// or an empty string if the response is sent uncompressed. Brotli wins a tie, because it compresses better, and
+// a compressed response wins a tie with 'identity'. The result is not acceptable if neither a supported coding nor
+// 'identity' is accepted (RFC 9110 section 12.5.3).
+func negotiateEncoding(acceptEncoding string) (string, bool) {
+	if acceptEncoding == "" {
+		return "", true
+	}
+
+	qualities := make(map[string]float64)
+	for _, codingValue := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(codingValue, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(param, "=")
+			if !strings.EqualFold(strings.TrimSpace(name), "q") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				parsed = 0
+			}
+			quality = parsed
+		}
+		qualities[coding] = quality
+	}
+
+	// a coding that is not listed has the q-value of '*', or is not acceptable without it
+	encoding, bestQuality := "", qualities["identity"]
+	for _, candidate := range []string{encodingBrotli, encodingGzip} {
+		quality, listed := qualities[candidate]
+		if !listed {
+			quality = qualities["*"]
+		}
+		if quality > 0 && (quality > bestQuality || (encoding == "" && quality == bestQuality)) {
+			encoding, bestQuality = candidate, quality
+		}
+	}
+	if encoding != "" {
+		return encoding, true
+	}
+
+	// the uncompressed response is acceptable unless 'identity;q=0', or '*;q=0' without 'identity', is sent
+	identityQuality, listed := qualities["identity"]
+	if !listed {
+		identityQuality, listed = qualities["*"]
+	}
+	return "", !listed || identityQuality > 0
+}
+
+func sendBody(
+	c *fiber.Ctx,
+	body []byte,
+	contentType string,
+	etag string,
+	lastModified time.Time,
+	hasNextKey bool,
+) error {
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, cacheControl(&config.GetConfig().ServerConfig, hasNextKey))
+	if !lastModified.IsZero() {
+		c.Set(fiber.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if isNotModified(c, etag, lastModified) {
+		c.Response().Header.Del(fiber.HeaderContentEncoding)
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Status(fiber.StatusOK).Send(body)
+}
+
+func cacheControl(serverConfig *config.ServerConfig, hasNextKey bool) string {
+	maxAge := serverConfig.CacheMaxAge
+	if hasNextKey {
+		maxAge = min(maxAge, serverConfig.CacheMaxAgeNextKey)
+	}
+
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
+// isNotModified evaluates the conditional request headers according to RFC 9110. If-Modified-Since is only
+// evaluated if If-None-Match is not sent.
+func isNotModified(c *fiber.Ctx, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	ifModifiedSince := c.Get(fiber.HeaderIfModifiedSince)
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	// the header has a precision of seconds
+	return !lastModified.Truncate(time.Second).After(since)
+}
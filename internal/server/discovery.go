@@ -0,0 +1,193 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"issuer-service-go/internal/jwks"
+	"os"
+	"strings"
+)
+
+const (
+	// allRealms is the key of the discovery metadata that applies to every realm.
+	allRealms = "*"
+	// issuerPlaceholder is replaced by the issuer URL of the realm in the configured discovery metadata.
+	issuerPlaceholder = "{issuer}"
+)
+
+// Discovery is the OpenID Provider metadata of a realm as defined by OpenID Connect Discovery 1.0. The
+// required fields are always set, the optional fields are omitted unless they are configured.
+type Discovery struct {
+	IssuerURL                                  string   `json:"issuer"`
+	AuthorizationEndpointURL                   string   `json:"authorization_endpoint"`
+	TokenEndpointURL                           string   `json:"token_endpoint,omitempty"`
+	UserinfoEndpointURL                        string   `json:"userinfo_endpoint,omitempty"`
+	JwksURL                                    string   `json:"jwks_uri"`
+	RegistrationEndpointURL                    string   `json:"registration_endpoint,omitempty"`
+	IntrospectionEndpointURL                   string   `json:"introspection_endpoint,omitempty"`
+	RevocationEndpointURL                      string   `json:"revocation_endpoint,omitempty"`
+	EndSessionEndpointURL                      string   `json:"end_session_endpoint,omitempty"`
+	ScopesSupported                            []string `json:"scopes_supported,omitempty"`
+	ResponseTypesSupported                     []string `json:"response_types_supported"`
+	ResponseModesSupported                     []string `json:"response_modes_supported,omitempty"`
+	GrantTypesSupported                        []string `json:"grant_types_supported,omitempty"`
+	AcrValuesSupported                         []string `json:"acr_values_supported,omitempty"`
+	SubjectTypesSupported                      []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported           []string `json:"id_token_signing_alg_values_supported"`
+	IDTokenEncryptionAlgValuesSupported        []string `json:"id_token_encryption_alg_values_supported,omitempty"`
+	IDTokenEncryptionEncValuesSupported        []string `json:"id_token_encryption_enc_values_supported,omitempty"`
+	UserinfoSigningAlgValuesSupported          []string `json:"userinfo_signing_alg_values_supported,omitempty"`
+	UserinfoEncryptionAlgValuesSupported       []string `json:"userinfo_encryption_alg_values_supported,omitempty"`
+	UserinfoEncryptionEncValuesSupported       []string `json:"userinfo_encryption_enc_values_supported,omitempty"`
+	RequestObjectSigningAlgValuesSupported     []string `json:"request_object_signing_alg_values_supported,omitempty"`
+	RequestObjectEncryptionAlgValuesSupported  []string `json:"request_object_encryption_alg_values_supported,omitempty"`
+	RequestObjectEncryptionEncValuesSupported  []string `json:"request_object_encryption_enc_values_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported,omitempty"`
+	IntrospectionEndpointAuthMethodsSupported  []string `json:"introspection_endpoint_auth_methods_supported,omitempty"`
+	RevocationEndpointAuthMethodsSupported     []string `json:"revocation_endpoint_auth_methods_supported,omitempty"`
+	CodeChallengeMethodsSupported              []string `json:"code_challenge_methods_supported,omitempty"`
+	DisplayValuesSupported                     []string `json:"display_values_supported,omitempty"`
+	ClaimTypesSupported                        []string `json:"claim_types_supported,omitempty"`
+	ClaimsSupported                            []string `json:"claims_supported,omitempty"`
+	ServiceDocumentation                       string   `json:"service_documentation,omitempty"`
+	ClaimsLocalesSupported                     []string `json:"claims_locales_supported,omitempty"`
+	UILocalesSupported                         []string `json:"ui_locales_supported,omitempty"`
+	ClaimsParameterSupported                   *bool    `json:"claims_parameter_supported,omitempty"`
+	RequestParameterSupported                  *bool    `json:"request_parameter_supported,omitempty"`
+	RequestURIParameterSupported               *bool    `json:"request_uri_parameter_supported,omitempty"`
+	RequireRequestURIRegistration              *bool    `json:"require_request_uri_registration,omitempty"`
+	OPPolicyURI                                string   `json:"op_policy_uri,omitempty"`
+	OPTosURI                                   string   `json:"op_tos_uri,omitempty"`
+	SignedMetadata                             string   `json:"signed_metadata,omitempty"`
+}
+
+// NewDiscoveryInfo creates the discovery document for the given realm. The advertised signing algorithms
+// are the algorithms of the served keys, falling back to RS256 if no key is available. The introspection
+// endpoint is only advertised if introspection is enabled.
+func NewDiscoveryInfo(issuerURL string, realm string, signingAlgs []string, introspectionEnabled bool) Discovery {
+	if len(signingAlgs) == 0 {
+		signingAlgs = []string{defaultSigningAlg}
+	}
+
+	discovery := Discovery{
+		IssuerURL: realmIssuerURL(issuerURL, realm),
+		JwksURL: fmt.Sprintf(
+			"%s/auth/realms/%s/protocol/openid-connect/certs",
+			issuerURL,
+			realm,
+		),
+		AuthorizationEndpointURL: fmt.Sprintf(
+			"%s/auth/realms/%s/protocol/openid-connect/auth",
+			issuerURL,
+			realm,
+		),
+		ResponseTypesSupported:           []string{"none"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: signingAlgs,
+	}
+	if introspectionEnabled {
+		discovery.IntrospectionEndpointURL = discovery.IssuerURL + "/protocol/openid-connect/token/introspect"
+		discovery.IntrospectionEndpointAuthMethodsSupported = []string{"client_secret_basic", "client_secret_post"}
+	}
+	return discovery
+}
+
+// realmIssuerURL returns the issuer of the tokens of the realm.
+func realmIssuerURL(issuerURL string, realm string) string {
+	return fmt.Sprintf("%s/auth/realms/%s", issuerURL, realm)
+}
+
+// signMetadata sets the 'signed_metadata' of RFC 8414 section 2.1, a JWT containing all other fields of the
+// discovery document and the issuer as 'iss' claim.
+func signMetadata(discovery *Discovery, metadataSigner *jwks.MetadataSigner) error {
+	discovery.SignedMetadata = ""
+	discoveryByteArray, err := json.Marshal(discovery)
+	if err != nil {
+		return fmt.Errorf("failed to marshal discovery document: %w", err)
+	}
+
+	var claims map[string]any
+	if err = json.Unmarshal(discoveryByteArray, &claims); err != nil {
+		return fmt.Errorf("failed to unmarshal discovery document: %w", err)
+	}
+	// RFC 8414 requires the issuer as 'iss' claim
+	claims["iss"] = discovery.IssuerURL
+
+	discovery.SignedMetadata, err = metadataSigner.Sign("", claims)
+	return err
+}
+
+// DiscoveryMetadata contains the configured metadata of the discovery documents per realm. The metadata of the
+// realm '*' applies to every realm and is overridden field by field by the metadata of the realm itself.
+type DiscoveryMetadata struct {
+	realms map[string]json.RawMessage
+}
+
+// NewDiscoveryMetadata reads the metadata from a JSON file, which maps the realms to (partial) discovery
+// documents. '{issuer}' in a value is replaced by the issuer URL of the realm. If file is empty, no metadata is
+// configured.
+func NewDiscoveryMetadata(file string) (*DiscoveryMetadata, error) {
+	if file == "" {
+		return &DiscoveryMetadata{}, nil
+	}
+
+	fileByteArray, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read discovery metadata: %w", err)
+	}
+
+	var realms map[string]json.RawMessage
+	if err = json.Unmarshal(fileByteArray, &realms); err != nil {
+		return nil, fmt.Errorf("failed to parse discovery metadata: %w", err)
+	}
+
+	var errs []error
+	for realm, metadata := range realms {
+		var discovery Discovery
+		decoder := json.NewDecoder(bytes.NewReader(metadata))
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(&discovery); err != nil {
+			errs = append(errs, fmt.Errorf("invalid discovery metadata of realm %s: %w", realm, err))
+		}
+	}
+	if err = errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return &DiscoveryMetadata{realms: realms}, nil
+}
+
+// apply overrides the fields of the discovery document with the configured metadata of the realm. The issuer
+// is never overridden, because it has to match the issuer of the tokens.
+func (m *DiscoveryMetadata) apply(discovery *Discovery, realm string) error {
+	if m == nil {
+		return nil
+	}
+
+	// the issuer URL is escaped, because it is inserted into JSON strings
+	escapedIssuer, err := json.Marshal(discovery.IssuerURL)
+	if err != nil {
+		return fmt.Errorf("failed to escape issuer URL: %w", err)
+	}
+	replacer := strings.NewReplacer(issuerPlaceholder, string(escapedIssuer[1:len(escapedIssuer)-1]))
+
+	issuerURL := discovery.IssuerURL
+	for _, key := range []string{allRealms, realm} {
+		metadata, exists := m.realms[key]
+		if !exists {
+			continue
+		}
+		if err = json.Unmarshal([]byte(replacer.Replace(string(metadata))), discovery); err != nil {
+			return fmt.Errorf("failed to apply discovery metadata of realm %s: %w", key, err)
+		}
+	}
+	discovery.IssuerURL = issuerURL
+	return nil
+}
@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"encoding/json"
+	"io"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/realm"
+	"issuer-service-go/internal/server"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDiscoveryMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		err  bool
+	}{
+		{name: "no file", file: ""},
+		{name: "valid file", file: "./router_testdata/discovery-metadata.json"},
+		{name: "unknown field", file: "./router_testdata/discovery-metadata-invalid.json", err: true},
+		{name: "no JSON", file: "./router_testdata/tls.kid", err: true},
+		{name: "missing file", file: "./router_testdata/missing.json", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.NewDiscoveryMetadata(tt.file)
+			assert.Equalf(t, tt.err, err != nil, "expected error: %v, got: %v", tt.err, err)
+		})
+	}
+}
+
+func TestDiscoveryRouteMetadata(t *testing.T) {
+	config.GetConfig().PathPrefix = ""
+
+	discoveryMetadata, err := server.NewDiscoveryMetadata("./router_testdata/discovery-metadata.json")
+	if err != nil {
+		t.Fatalf("failed to load discovery metadata: %v", err)
+	}
+
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(newDiscoveryTestProvider(t), server.HandlerOptions{
+		RealmRegistry:     realm.NewWildcardRegistry(),
+		DiscoveryMetadata: discoveryMetadata,
+		IssuerResolver:    newTestIssuerResolver(t),
+	}))
+
+	getDiscovery := func(realmName string) map[string]any {
+		req := httptest.NewRequest(http.MethodGet, "/auth/realms/"+realmName+"/.well-known/openid-configuration", nil)
+		req.Header.Set("X-Forwarded-Host", "example.com")
+		resp, err := srv.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var document map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+			t.Fatalf("failed to decode response body: %v", err)
+		}
+		return document
+	}
+
+	// the metadata of every realm is applied
+	document := getDiscovery("default")
+	assert.Equal(t, "https://example.com/auth/realms/default", document["issuer"])
+	assert.Equal(t, "https://example.com/auth/realms/default/protocol/openid-connect/token", document["token_endpoint"])
+	assert.Equal(t, []any{"client_credentials"}, document["grant_types_supported"])
+	assert.Equal(t, []any{"none"}, document["response_types_supported"])
+	assert.Equal(t, []any{"openid"}, document["scopes_supported"])
+
+	// unset fields are omitted
+	assert.NotContains(t, document, "introspection_endpoint")
+	assert.NotContains(t, document, "claims_supported")
+	assert.NotContains(t, document, "claims_parameter_supported")
+
+	// the metadata of the realm overrides the metadata of every realm, except the issuer
+	document = getDiscovery("other-realm")
+	assert.Equal(t, "https://example.com/auth/realms/other-realm", document["issuer"])
+	assert.Equal(t, "https://example.com/auth/realms/other-realm/protocol/openid-connect/token",
+		document["token_endpoint"])
+	assert.Equal(t, []any{"code"}, document["response_types_supported"])
+	assert.Equal(t, []any{"openid", "profile"}, document["scopes_supported"])
+	assert.Equal(t, []any{"sub", "iss", "aud", "exp", "iat"}, document["claims_supported"])
+	claimsParameterSupported, ok := document["claims_parameter_supported"].(bool)
+	assert.True(t, ok, "explicitly unsupported features must be advertised")
+	assert.False(t, claimsParameterSupported)
+}
+
+func TestAuthorizationServerMetadataRoute(t *testing.T) {
+	config.GetConfig().PathPrefix = "/spacegate"
+	t.Cleanup(func() { config.GetConfig().PathPrefix = "" })
+
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(newDiscoveryTestProvider(t), server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	}))
+
+	get := func(route string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, route, nil)
+		req.Header.Set("X-Forwarded-Host", "example.com")
+		resp, err := srv.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	_, openIDConfiguration := get("/auth/realms/default/.well-known/openid-configuration")
+
+	tests := []struct {
+		description  string
+		route        string
+		expectedCode int
+	}{
+		{
+			description:  "well-known path inserted before the issuer path",
+			route:        "/.well-known/oauth-authorization-server/auth/realms/default",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "well-known path inserted before the prefixed issuer path",
+			route:        "/.well-known/oauth-authorization-server/spacegate/auth/realms/default",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "well-known path appended to the issuer path",
+			route:        "/auth/realms/default/.well-known/oauth-authorization-server",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			statusCode, body := get(tt.route)
+			assert.Equal(t, tt.expectedCode, statusCode)
+			if tt.expectedCode == http.StatusOK {
+				assert.JSONEq(t, openIDConfiguration, body, "both documents must be consistent")
+			}
+		})
+	}
+}
+
+func newDiscoveryTestProvider(t *testing.T) jwks.Provider {
+	t.Helper()
+
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./router_testdata/",
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	return jwksProvider
+}
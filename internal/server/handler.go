@@ -5,95 +5,184 @@
 package server
 
 import (
-	"fmt"
-	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/introspection"
 	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/realm"
+	"issuer-service-go/internal/validation"
+	"sync/atomic"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	defaultSigningAlg = "RS256"
+
+	// mimeJwkSetJwt is the media type of the signed JWKS, 'typ' of the JWT is the media type without 'application/'.
+	mimeJwkSetJwt = "application/jwk-set+jwt"
+	typJwkSetJwt  = "jwk-set+jwt"
+)
+
 type HandlerInterface interface {
 	DiscoveryHandler(c *fiber.Ctx) error
 	JwksHandler(c *fiber.Ctx) error
 	IssuerHandler(c *fiber.Ctx) error
+	ValidationHandler(c *fiber.Ctx) error
+	IntrospectionHandler(c *fiber.Ctx) error
+	LivenessHandler(c *fiber.Ctx) error
+	ReadinessHandler(c *fiber.Ctx) error
+	StartupHandler(c *fiber.Ctx) error
 }
 
 type Handler struct {
-	jwksProvider jwks.Provider
+	jwksProvider      jwks.Provider
+	realmRegistry     *realm.Registry
+	discoveryMetadata *DiscoveryMetadata
+	introspection     *introspection.Clients
+	metadataSigner    *jwks.MetadataSigner
+	issuerResolver    *IssuerResolver
+
+	// started is set once the startup probe succeeded
+	started atomic.Bool
 }
 
 type JwksResponse struct {
 	Keys []*jwks.Jwk `json:"keys"`
 }
 
-func NewHandler(jwksProvider jwks.Provider) *Handler {
-	return &Handler{
-		jwksProvider: jwksProvider,
-	}
+// ValidationRequest is the body of a request to the validation endpoint.
+type ValidationRequest struct {
+	Token    string `json:"token"`
+	Audience string `json:"audience"` // optional, the audience is only checked if it is set
+}
+
+// ErrorResponse is the Keycloak compatible error body.
+type ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
 }
 
-type Discovery struct {
-	IssuerURL                        string   `json:"issuer"`
-	JwksURL                          string   `json:"jwks_uri"`
-	AuthorizationEndpointURL         string   `json:"authorization_endpoint"`
-	ResponseTypesSupported           []string `json:"response_types_supported"`
-	SubjectTypesSupported            []string `json:"subject_types_supported"`
-	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+// HandlerOptions configures the optional features of the Handler. A feature is disabled if its field is nil.
+type HandlerOptions struct {
+	// RealmRegistry contains the served realms, only the default realm is served if it is nil, like if no realm is
+	// configured
+	RealmRegistry *realm.Registry
+	// DiscoveryMetadata is added to the discovery document
+	DiscoveryMetadata *DiscoveryMetadata
+	// IntrospectionClients are the clients allowed to call the introspection endpoint
+	IntrospectionClients *introspection.Clients
+	// MetadataSigner signs the discovery metadata and the JWKS
+	MetadataSigner *jwks.MetadataSigner
+	// IssuerResolver resolves the issuer URL, it is required by every endpoint that contains the issuer URL
+	IssuerResolver *IssuerResolver
 }
 
-func NewDiscoveryInfo(issuerURL string, realm string) Discovery {
-	return Discovery{
-		IssuerURL: fmt.Sprintf("%s/auth/realms/%s", issuerURL, realm),
-		JwksURL: fmt.Sprintf(
-			"%s/auth/realms/%s/protocol/openid-connect/certs",
-			issuerURL,
-			realm,
-		),
-		AuthorizationEndpointURL: fmt.Sprintf(
-			"%s/auth/realms/%s/protocol/openid-connect/auth",
-			issuerURL,
-			realm,
-		),
-		ResponseTypesSupported:           []string{"none"},
-		SubjectTypesSupported:            []string{"public"},
-		IDTokenSigningAlgValuesSupported: []string{"RS256"},
+func NewHandler(jwksProvider jwks.Provider, options HandlerOptions) *Handler {
+	realmRegistry := options.RealmRegistry
+	if realmRegistry == nil {
+		realmRegistry = realm.NewDefaultRegistry()
+	}
+
+	return &Handler{
+		jwksProvider:      jwksProvider,
+		realmRegistry:     realmRegistry,
+		discoveryMetadata: options.DiscoveryMetadata,
+		introspection:     options.IntrospectionClients,
+		metadataSigner:    options.MetadataSigner,
+		issuerResolver:    options.IssuerResolver,
 	}
 }
 
 func (h *Handler) DiscoveryHandler(c *fiber.Ctx) error {
 	log.Debug().Msg("Request received on discovery endpoint")
 	realm := c.Params("realm")
+	if !h.realmRegistry.Exists(realm) {
+		return sendRealmNotFound(c, realm)
+	}
 
 	log.Debug().Msgf("Request with following headers: %+v", c.GetReqHeaders())
-	host := c.Get("X-Forwarded-Host")
-	if host == "" {
-		log.Error().Msg("X-Forwarded-Host header must be set in the request")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Error{
-			Code:    fiber.StatusBadRequest,
-			Message: "X-Forwarded-Host header must be set in the request",
-		})
+	h.issuerResolver.vary(c)
+	issuerURL, err := h.issuerResolver.Resolve(c, realm)
+	if err != nil {
+		return sendIssuerNotResolved(c, err)
 	}
-	issuerURL := "https://" + host + config.GetConfig().PathPrefix
 
-	return c.Status(fiber.StatusOK).JSON(NewDiscoveryInfo(issuerURL, realm))
+	signingAlgs := jwks.SigningAlgs(h.jwksProvider.GetJwks(realm))
+	discovery := NewDiscoveryInfo(issuerURL, realm, signingAlgs, h.introspection.Enabled())
+	if err = h.discoveryMetadata.apply(&discovery, realm); err != nil {
+		return err
+	}
+	lastModified := h.jwksProvider.LastModified(realm)
+	if h.metadataSigner != nil {
+		if err = signMetadata(&discovery, h.metadataSigner); err != nil {
+			return err
+		}
+		// the signed metadata changes whenever it is signed again, which the ETag reflects but not the keys
+		lastModified = time.Time{}
+	}
+
+	return sendCacheable(c, discovery, lastModified, h.jwksProvider.HasNextKey(realm))
 }
 
 func (h *Handler) JwksHandler(c *fiber.Ctx) error {
 	realm := c.Params("realm")
 	log.Debug().Msgf("Request received on certs endpoint for realm %s", realm)
+	if !h.realmRegistry.Exists(realm) {
+		return sendRealmNotFound(c, realm)
+	}
+
+	if h.metadataSigner != nil {
+		c.Vary(fiber.HeaderAccept)
+		if c.Accepts(fiber.MIMEApplicationJSON, mimeJwkSetJwt) == mimeJwkSetJwt {
+			return h.sendSignedJwks(c, realm)
+		}
+	}
 
-	info := h.jwksProvider.GetJwks()
+	if snapshotProvider, ok := h.jwksProvider.(jwks.SnapshotProvider); ok {
+		if snapshot := snapshotProvider.Snapshot(realm); snapshot != nil {
+			return sendSnapshot(c, snapshot)
+		}
+	}
+
+	info := h.jwksProvider.GetJwks(realm)
 	response := &JwksResponse{
 		Keys: info,
 	}
 
-	return c.Status(fiber.StatusOK).JSON(response)
+	return sendCacheable(c, response, h.jwksProvider.LastModified(realm), h.jwksProvider.HasNextKey(realm))
+}
+
+// sendSignedJwks sends the JWKS as JWT signed with the metadata signing key. The issuer of the realm is the
+// issuer and subject of the JWT. It is sent without Last-Modified, because the JWT changes whenever it is signed
+// again, so it is only revalidated by its ETag.
+func (h *Handler) sendSignedJwks(c *fiber.Ctx, realm string) error {
+	h.issuerResolver.vary(c)
+	issuerURL, err := h.issuerResolver.Resolve(c, realm)
+	if err != nil {
+		return sendIssuerNotResolved(c, err)
+	}
+	issuer := realmIssuerURL(issuerURL, realm)
+
+	token, err := h.metadataSigner.Sign(typJwkSetJwt, map[string]any{
+		"iss":  issuer,
+		"sub":  issuer,
+		"keys": h.jwksProvider.GetJwks(realm),
+	})
+	if err != nil {
+		return err
+	}
+
+	body := []byte(token)
+	return sendBody(c, body, mimeJwkSetJwt, jwks.ETag(body), time.Time{}, h.jwksProvider.HasNextKey(realm))
 }
 
 func (h *Handler) IssuerHandler(c *fiber.Ctx) error {
 	realm := c.Params("realm")
 	log.Debug().Msgf("Request received on issuer endpoint for realm %s", realm)
+	if !h.realmRegistry.Exists(realm) {
+		return sendRealmNotFound(c, realm)
+	}
 
 	defaultRealm := h.jwksProvider.GetDefaultRealm(realm)
 	if defaultRealm == nil {
@@ -104,5 +193,112 @@ func (h *Handler) IssuerHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(defaultRealm)
+	// the realm info was served without issuer before, so the service URLs are left out if it cannot be resolved
+	response := *defaultRealm
+	h.issuerResolver.vary(c)
+	if issuerURL, err := h.issuerResolver.Resolve(c, realm); err == nil {
+		issuer := realmIssuerURL(issuerURL, realm)
+		response.TokenService = issuer + "/protocol/openid-connect"
+		response.AccountService = issuer + "/account"
+	} else {
+		log.Debug().Msgf("service URLs of realm %s are left out: %v", realm, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// ValidationHandler verifies a token against the keys of the realm and responds with a diagnosis of every
+// check, so that clients can find out why a gateway issued token is rejected.
+func (h *Handler) ValidationHandler(c *fiber.Ctx) error {
+	realm := c.Params("realm")
+	log.Debug().Msgf("Request received on validation endpoint for realm %s", realm)
+	if !h.realmRegistry.Exists(realm) {
+		return sendRealmNotFound(c, realm)
+	}
+
+	issuerURL, err := h.issuerResolver.Resolve(c, realm)
+	if err != nil {
+		return sendIssuerNotResolved(c, err)
+	}
+
+	var request ValidationRequest
+	if err = c.BodyParser(&request); err != nil || request.Token == "" {
+		log.Debug().Err(err).Msg("invalid validation request")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Error{
+			Code:    fiber.StatusBadRequest,
+			Message: "request body must be a JSON object with a token",
+		})
+	}
+
+	diagnosis := validation.Validate(request.Token, h.jwksProvider.GetJwks(realm), validation.Expectations{
+		Issuer:   realmIssuerURL(issuerURL, realm),
+		Audience: request.Audience,
+		Now:      time.Now(),
+	})
+	return c.Status(fiber.StatusOK).JSON(diagnosis)
+}
+
+// IntrospectionHandler responds with the RFC 7662 introspection response of a token, which is verified against
+// the keys of the realm. Clients authenticate with the configured credentials, either with the 'Basic' scheme
+// or with the 'client_id' and 'client_secret' form parameters.
+func (h *Handler) IntrospectionHandler(c *fiber.Ctx) error {
+	realm := c.Params("realm")
+	log.Debug().Msgf("Request received on introspection endpoint for realm %s", realm)
+	if !h.realmRegistry.Exists(realm) {
+		return sendRealmNotFound(c, realm)
+	}
+	if !h.introspection.Enabled() {
+		return notImplemented(c)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	clientID, clientSecret, ok := introspection.ParseBasicAuth(c.Get(fiber.HeaderAuthorization))
+	if !ok {
+		clientID, clientSecret = c.FormValue("client_id"), c.FormValue("client_secret")
+	}
+	if !h.introspection.Authenticate(clientID, clientSecret) {
+		log.Warn().Msgf("introspection client '%s' of realm %s failed to authenticate", clientID, realm)
+		c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="`+realm+`"`)
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Error:            "invalid_client",
+			ErrorDescription: "Authentication failed",
+		})
+	}
+
+	token := c.FormValue("token")
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:            "invalid_request",
+			ErrorDescription: "Token not provided",
+		})
+	}
+
+	issuerURL, err := h.issuerResolver.Resolve(c, realm)
+	if err != nil {
+		return sendIssuerNotResolved(c, err)
+	}
+
+	diagnosis := validation.Validate(token, h.jwksProvider.GetJwks(realm), validation.Expectations{
+		Issuer: realmIssuerURL(issuerURL, realm),
+		Now:    time.Now(),
+	})
+	if !diagnosis.Valid {
+		log.Debug().Msgf("introspected token of realm %s is inactive, check '%s' failed", realm, diagnosis.FailedCheck)
+	}
+	return c.Status(fiber.StatusOK).JSON(introspection.NewResponse(diagnosis))
+}
+
+// sendIssuerNotResolved responds with 400 if the issuer could not be resolved from the request.
+func sendIssuerNotResolved(c *fiber.Ctx, err error) error {
+	log.Error().Msgf("failed to resolve the issuer: %v", err)
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Error{
+		Code:    fiber.StatusBadRequest,
+		Message: err.Error(),
+	})
+}
+
+// sendRealmNotFound responds with the error body of Keycloak for a realm that does not exist.
+func sendRealmNotFound(c *fiber.Ctx, realm string) error {
+	log.Debug().Msgf("realm %s does not exist", realm)
+	return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Error: "Realm does not exist"})
 }
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/server"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// renderingProvider hides the snapshot of the wrapped provider, so the JWKS response is rendered per request.
+type renderingProvider struct {
+	jwks.Provider
+}
+
+func newBenchmarkServer(b *testing.B, snapshot bool) *server.FiberServer {
+	b.Helper()
+
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./router_testdata/",
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+
+	jwksProvider, err := jwks.NewFileProvider(b.Context(), jwksConfig)
+	if err != nil {
+		b.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+
+	var provider jwks.Provider = jwksProvider
+	if !snapshot {
+		provider = renderingProvider{Provider: jwksProvider}
+	}
+
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(provider, server.HandlerOptions{}))
+	return srv
+}
+
+// BenchmarkJwksHandler compares serving the JWKS from the snapshot with rendering it per request.
+func BenchmarkJwksHandler(b *testing.B) {
+	benchmarks := []struct {
+		name           string
+		snapshot       bool
+		acceptEncoding string
+	}{
+		{name: "rendered", snapshot: false},
+		{name: "snapshot", snapshot: true},
+		{name: "snapshot-gzip", snapshot: true, acceptEncoding: "gzip"},
+		{name: "snapshot-brotli", snapshot: true, acceptEncoding: "br"},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			handler := newBenchmarkServer(b, bm.snapshot).Handler()
+
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				var ctx fasthttp.RequestCtx
+				for pb.Next() {
+					ctx.Request.Reset()
+					ctx.Response.Reset()
+					ctx.Request.SetRequestURI("/auth/realms/default/protocol/openid-connect/certs")
+					if bm.acceptEncoding != "" {
+						ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, bm.acceptEncoding)
+					}
+
+					handler(&ctx)
+					if ctx.Response.StatusCode() != fasthttp.StatusOK {
+						// FailNow must not be called outside the goroutine running the benchmark
+						b.Errorf("unexpected status code %d", ctx.Response.StatusCode())
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	HealthStatusUp       = "UP"
+	HealthStatusDegraded = "DEGRADED"
+	HealthStatusDown     = "DOWN"
+
+	HealthCheckActiveKey         = "active-key"
+	HealthCheckActiveCertificate = "active-certificate"
+	HealthCheckReload            = "reload"
+	HealthCheckSlot              = "slot"
+
+	slotActive = "active"
+)
+
+// HealthResponse is the body of the health probes. The service is down if any check is down and degraded if any
+// check is degraded, which does not affect the status code.
+type HealthResponse struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the result of a single check of the keys of a realm.
+type HealthCheck struct {
+	Name    string `json:"name"`
+	Realm   string `json:"realm,omitempty"` // empty for the keys of all realms without keys of their own
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// LivenessHandler reports that the process is able to serve requests. It does not depend on the keys, so a pod is
+// not restarted because of a broken key source, which a restart would not fix.
+func (h *Handler) LivenessHandler(c *fiber.Ctx) error {
+	return sendHealth(c, HealthResponse{Status: HealthStatusUp})
+}
+
+// ReadinessHandler reports whether an active key is loaded for every realm, its certificate is not expired and
+// the active key was reloaded successfully within the last HEALTH_MAX_MISSED_RELOADS intervals. Slots that failed
+// to load are reported as degraded, because the last successfully loaded keys are still served.
+func (h *Handler) ReadinessHandler(c *fiber.Ctx) error {
+	now := time.Now()
+	maxMissedReloads := config.GetConfig().ServerConfig.HealthMaxMissedReloads
+
+	var checks []HealthCheck
+	for _, realm := range jwks.ProviderRealms(h.jwksProvider) {
+		activeJwk := h.activeJwk(realm)
+		checks = append(checks, checkActiveKey(realm, activeJwk), checkActiveCertificate(realm, activeJwk, now))
+		if reloadCheck, ok := h.checkReload(realm, now, maxMissedReloads); ok {
+			checks = append(checks, reloadCheck)
+		}
+		checks = append(checks, h.checkSlots(realm)...)
+	}
+	return sendHealth(c, newHealthResponse(checks))
+}
+
+// StartupHandler reports whether the active key of every realm was loaded. Once it succeeded, it always succeeds,
+// because readiness takes over afterward.
+func (h *Handler) StartupHandler(c *fiber.Ctx) error {
+	if h.started.Load() {
+		return sendHealth(c, HealthResponse{Status: HealthStatusUp})
+	}
+
+	var checks []HealthCheck
+	for _, realm := range jwks.ProviderRealms(h.jwksProvider) {
+		checks = append(checks, checkActiveKey(realm, h.activeJwk(realm)))
+	}
+	response := newHealthResponse(checks)
+	if response.Status == HealthStatusUp {
+		h.started.Store(true)
+	}
+	return sendHealth(c, response)
+}
+
+// activeJwk returns the loaded active key of the realm, or nil if there is none. It is returned even if it is
+// expired and therefore not published, so the probes can tell an expired key from a missing one.
+func (h *Handler) activeJwk(realm string) *jwks.Jwk {
+	for _, jwk := range jwks.LoadedJwks(h.jwksProvider, realm) {
+		if jwk.Slot == slotActive {
+			return jwk
+		}
+	}
+	return nil
+}
+
+// checkReload checks the last reload of the keys of the realm. It returns false if the keys are not reloaded
+// periodically (e.g. they are watched), so there is no interval to compare against.
+func (h *Handler) checkReload(realm string, now time.Time, maxMissedReloads int) (HealthCheck, bool) {
+	reloadStatusProvider, ok := h.jwksProvider.(jwks.ReloadStatusProvider)
+	if !ok {
+		return HealthCheck{}, false
+	}
+	lastReload, interval := reloadStatusProvider.LastReload(realm)
+	if interval == 0 || maxMissedReloads <= 0 {
+		return HealthCheck{}, false
+	}
+
+	check := HealthCheck{Name: HealthCheckReload, Realm: realm, Status: HealthStatusUp}
+	maxAge := time.Duration(maxMissedReloads) * interval
+	if age := now.Sub(lastReload); age > maxAge {
+		check.Status = HealthStatusDown
+		check.Message = fmt.Sprintf("last successful reload at %s is older than %s", lastReload.Format(time.RFC3339),
+			maxAge)
+		return check, true
+	}
+	check.Message = "last successful reload at " + lastReload.Format(time.RFC3339)
+	return check, true
+}
+
+// checkSlots reports every slot of the realm that failed to load as degraded.
+func (h *Handler) checkSlots(realm string) []HealthCheck {
+	slotStatusProvider, ok := h.jwksProvider.(jwks.SlotStatusProvider)
+	if !ok {
+		return nil
+	}
+
+	var checks []HealthCheck
+	for _, status := range slotStatusProvider.SlotStatus(realm) {
+		if status.Error == "" {
+			continue
+		}
+		message := fmt.Sprintf("failed to load %s certificate: %s", status.Slot, status.Error)
+		if status.Retained {
+			message += ", retaining key with kid " + status.Kid
+		}
+		checks = append(checks, HealthCheck{
+			Name:    HealthCheckSlot,
+			Realm:   realm,
+			Status:  HealthStatusDegraded,
+			Message: message,
+		})
+	}
+	return checks
+}
+
+func checkActiveKey(realm string, activeJwk *jwks.Jwk) HealthCheck {
+	if activeJwk == nil {
+		return HealthCheck{
+			Name:    HealthCheckActiveKey,
+			Realm:   realm,
+			Status:  HealthStatusDown,
+			Message: "no active key available",
+		}
+	}
+	return HealthCheck{
+		Name:    HealthCheckActiveKey,
+		Realm:   realm,
+		Status:  HealthStatusUp,
+		Message: "active key with kid " + activeJwk.Kid,
+	}
+}
+
+func checkActiveCertificate(realm string, activeJwk *jwks.Jwk, now time.Time) HealthCheck {
+	check := HealthCheck{Name: HealthCheckActiveCertificate, Realm: realm, Status: HealthStatusUp}
+	switch {
+	case activeJwk == nil:
+		check.Status = HealthStatusDown
+		check.Message = "no active certificate available"
+	case activeJwk.IsExpiredAt(now):
+		check.Status = HealthStatusDown
+		check.Message = "active certificate expired at " + activeJwk.NotAfter.Format(time.RFC3339)
+	case !activeJwk.NotAfter.IsZero():
+		check.Message = "active certificate is valid until " + activeJwk.NotAfter.Format(time.RFC3339)
+	}
+	return check
+}
+
+func newHealthResponse(checks []HealthCheck) HealthResponse {
+	response := HealthResponse{Status: HealthStatusUp, Checks: checks}
+	for _, check := range checks {
+		switch {
+		case check.Status == HealthStatusDown:
+			response.Status = HealthStatusDown
+		case check.Status == HealthStatusDegraded && response.Status == HealthStatusUp:
+			response.Status = HealthStatusDegraded
+		}
+	}
+	return response
+}
+
+// sendHealth responds with 503 if the service is down, otherwise with 200.
+func sendHealth(c *fiber.Ctx, response HealthResponse) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	if response.Status == HealthStatusDown {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(response)
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
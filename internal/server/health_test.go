@@ -0,0 +1,253 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"encoding/json"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/server"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// healthTestProvider serves the given keys for every realm and reports the given reload and slot state. Expired
+// keys are not published if excludeExpired is set.
+type healthTestProvider struct {
+	keys           []*jwks.Jwk
+	excludeExpired bool
+	lastReload     time.Time
+	reloadInterval time.Duration
+	slotStatus     []jwks.SlotStatus
+}
+
+func (p *healthTestProvider) GetJwks(_ string) []*jwks.Jwk {
+	if !p.excludeExpired {
+		return p.keys
+	}
+	var keys []*jwks.Jwk
+	for _, jwk := range p.keys {
+		if !jwk.IsExpiredAt(time.Now()) {
+			keys = append(keys, jwk)
+		}
+	}
+	return keys
+}
+
+func (p *healthTestProvider) LoadedJwks(_ string) []*jwks.Jwk {
+	return p.keys
+}
+
+func (p *healthTestProvider) GetDefaultRealm(_ string) *jwks.DefaultRealm {
+	return nil
+}
+
+func (p *healthTestProvider) LastModified(_ string) time.Time {
+	return time.Time{}
+}
+
+func (p *healthTestProvider) HasNextKey(_ string) bool {
+	return false
+}
+
+func (p *healthTestProvider) LastReload(_ string) (time.Time, time.Duration) {
+	return p.lastReload, p.reloadInterval
+}
+
+func (p *healthTestProvider) SlotStatus(_ string) []jwks.SlotStatus {
+	return p.slotStatus
+}
+
+func TestHealthRoutes(t *testing.T) {
+	config.GetConfig().ServerConfig.HealthMaxMissedReloads = 3
+
+	activeJwk := &jwks.Jwk{Kid: "active", Slot: "active", NotAfter: time.Now().Add(time.Hour)}
+	expiredJwk := &jwks.Jwk{Kid: "expired", Slot: "active", NotAfter: time.Now().Add(-time.Hour)}
+	previousJwk := &jwks.Jwk{Kid: "previous", Slot: "previous", NotAfter: time.Now().Add(time.Hour)}
+
+	tests := []struct {
+		description       string
+		provider          *healthTestProvider
+		route             string
+		expectedCode      int
+		expectedDownCheck string
+	}{
+		{
+			description:  "liveness does not depend on the keys",
+			provider:     &healthTestProvider{},
+			route:        "/health/live",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "ready with an active key that was reloaded recently",
+			provider:     &healthTestProvider{keys: []*jwks.Jwk{activeJwk}, lastReload: time.Now(), reloadInterval: time.Second},
+			route:        "/health/ready",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "ready with an active key that is not reloaded periodically",
+			provider:     &healthTestProvider{keys: []*jwks.Jwk{activeJwk}},
+			route:        "/health/ready",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:       "not ready without active key",
+			provider:          &healthTestProvider{keys: []*jwks.Jwk{previousJwk}},
+			route:             "/health/ready",
+			expectedCode:      http.StatusServiceUnavailable,
+			expectedDownCheck: server.HealthCheckActiveKey,
+		},
+		{
+			description:       "not ready with an expired active certificate",
+			provider:          &healthTestProvider{keys: []*jwks.Jwk{expiredJwk}},
+			route:             "/health/ready",
+			expectedCode:      http.StatusServiceUnavailable,
+			expectedDownCheck: server.HealthCheckActiveCertificate,
+		},
+		{
+			description:       "not ready with an expired active certificate that is not published",
+			provider:          &healthTestProvider{keys: []*jwks.Jwk{expiredJwk}, excludeExpired: true},
+			route:             "/health/ready",
+			expectedCode:      http.StatusServiceUnavailable,
+			expectedDownCheck: server.HealthCheckActiveCertificate,
+		},
+		{
+			description: "not ready if the last reload is older than the missed reloads",
+			provider: &healthTestProvider{
+				keys:           []*jwks.Jwk{activeJwk},
+				lastReload:     time.Now().Add(-4 * time.Second),
+				reloadInterval: time.Second,
+			},
+			route:             "/health/ready",
+			expectedCode:      http.StatusServiceUnavailable,
+			expectedDownCheck: server.HealthCheckReload,
+		},
+		{
+			description:  "started with an active key",
+			provider:     &healthTestProvider{keys: []*jwks.Jwk{activeJwk}},
+			route:        "/health/startup",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:       "not started without active key",
+			provider:          &healthTestProvider{},
+			route:             "/health/startup",
+			expectedCode:      http.StatusServiceUnavailable,
+			expectedDownCheck: server.HealthCheckActiveKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			srv := server.New()
+			srv.RegisterRoutes(server.NewHandler(tt.provider, server.HandlerOptions{
+				IssuerResolver: newTestIssuerResolver(t),
+			}))
+
+			resp, err := srv.Test(httptest.NewRequest(http.MethodGet, tt.route, nil), -1)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+			assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+			var health server.HealthResponse
+			if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if tt.expectedDownCheck == "" {
+				assert.Equal(t, server.HealthStatusUp, health.Status)
+				return
+			}
+			assert.Equal(t, server.HealthStatusDown, health.Status)
+			assert.Contains(t, downChecks(health), tt.expectedDownCheck)
+		})
+	}
+}
+
+func TestReadinessWithFailedSlot(t *testing.T) {
+	provider := &healthTestProvider{
+		keys:           []*jwks.Jwk{{Kid: "active", Slot: "active", NotAfter: time.Now().Add(time.Hour)}},
+		lastReload:     time.Now(),
+		reloadInterval: time.Second,
+		slotStatus: []jwks.SlotStatus{
+			{Slot: "next", Error: "failed to decode PEM block"},
+			{Slot: "active", Loaded: true, Kid: "active"},
+			{Slot: "previous", Error: "failed to decode PEM block", Retained: true, Kid: "previous"},
+		},
+	}
+
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(provider, server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	}))
+
+	resp, err := srv.Test(httptest.NewRequest(http.MethodGet, "/health/ready", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	// the active key is served, so a failed next or previous slot does not take the pod out of service
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var health server.HealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	assert.Equal(t, server.HealthStatusDegraded, health.Status)
+
+	var slotMessages []string
+	for _, check := range health.Checks {
+		if check.Name == server.HealthCheckSlot {
+			assert.Equal(t, server.HealthStatusDegraded, check.Status)
+			slotMessages = append(slotMessages, check.Message)
+		}
+	}
+	if assert.Len(t, slotMessages, 2) {
+		assert.Contains(t, slotMessages[0], "next")
+		assert.Contains(t, slotMessages[1], "retaining key with kid previous")
+	}
+}
+
+func TestStartupProbeStaysUp(t *testing.T) {
+	provider := &healthTestProvider{keys: []*jwks.Jwk{{Kid: "active", Slot: "active"}}}
+
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(provider, server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	}))
+
+	getStatus := func(route string) int {
+		resp, err := srv.Test(httptest.NewRequest(http.MethodGet, route, nil), -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, getStatus("/health/startup"))
+
+	// once started, a lost key is reported by the readiness probe only
+	provider.keys = nil
+	assert.Equal(t, http.StatusOK, getStatus("/health/startup"))
+	assert.Equal(t, http.StatusServiceUnavailable, getStatus("/health/ready"))
+	assert.Equal(t, http.StatusOK, getStatus("/health/live"))
+}
+
+func downChecks(health server.HealthResponse) []string {
+	var names []string
+	for _, check := range health.Checks {
+		if check.Status == server.HealthStatusDown {
+			names = append(names, check.Name)
+		}
+	}
+	return names
+}
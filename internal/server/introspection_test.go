@@ -0,0 +1,185 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/introspection"
+	"issuer-service-go/internal/server"
+	"issuer-service-go/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntrospectionRoute(t *testing.T) {
+	config.GetConfig().PathPrefix = ""
+
+	introspectionClients, err := introspection.NewClients("./router_testdata/introspection-clients")
+	if err != nil {
+		t.Fatalf("failed to read introspection clients: %v", err)
+	}
+
+	certificate := testutil.NewCertificate(t, testutil.CertificateOptions{})
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(newValidationTestProvider(t, certificate), server.HandlerOptions{
+		IntrospectionClients: introspectionClients,
+		IssuerResolver:       newTestIssuerResolver(t),
+	}))
+
+	expiry := time.Now().Add(time.Minute).Unix()
+	validToken := signTestToken(t, certificate, map[string]any{
+		"iss":   "https://example.com/auth/realms/default",
+		"exp":   expiry,
+		"sub":   "1234",
+		"azp":   "gateway-client",
+		"scope": "openid",
+	})
+	expiredToken := signTestToken(t, certificate, map[string]any{
+		"iss": "https://example.com/auth/realms/default",
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	})
+	basicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("resource-server:s3cr3t"))
+
+	tests := []struct {
+		description      string
+		form             url.Values
+		authorization    string
+		expectedCode     int
+		expectedResponse map[string]any
+	}{
+		{
+			description:   "active token",
+			form:          url.Values{"token": {validToken}},
+			authorization: basicAuth,
+			expectedCode:  http.StatusOK,
+			expectedResponse: map[string]any{
+				"active":    true,
+				"iss":       "https://example.com/auth/realms/default",
+				"exp":       float64(expiry),
+				"sub":       "1234",
+				"azp":       "gateway-client",
+				"client_id": "gateway-client",
+				"scope":     "openid",
+			},
+		},
+		{
+			description:      "expired token is inactive",
+			form:             url.Values{"token": {expiredToken}},
+			authorization:    basicAuth,
+			expectedCode:     http.StatusOK,
+			expectedResponse: map[string]any{"active": false},
+		},
+		{
+			description:      "malformed token is inactive",
+			form:             url.Values{"token": {"not-a-jwt"}},
+			authorization:    basicAuth,
+			expectedCode:     http.StatusOK,
+			expectedResponse: map[string]any{"active": false},
+		},
+		{
+			description: "client credentials in the form",
+			form: url.Values{
+				"token":         {expiredToken},
+				"client_id":     {"resource-server"},
+				"client_secret": {"s3cr3t"},
+			},
+			expectedCode:     http.StatusOK,
+			expectedResponse: map[string]any{"active": false},
+		},
+		{
+			description:  "missing client credentials",
+			form:         url.Values{"token": {validToken}},
+			expectedCode: http.StatusUnauthorized,
+			expectedResponse: map[string]any{
+				"error":             "invalid_client",
+				"error_description": "Authentication failed",
+			},
+		},
+		{
+			description:   "wrong client secret",
+			form:          url.Values{"token": {validToken}},
+			authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte("resource-server:wrong")),
+			expectedCode:  http.StatusUnauthorized,
+			expectedResponse: map[string]any{
+				"error":             "invalid_client",
+				"error_description": "Authentication failed",
+			},
+		},
+		{
+			description:   "missing token",
+			form:          url.Values{},
+			authorization: basicAuth,
+			expectedCode:  http.StatusBadRequest,
+			expectedResponse: map[string]any{
+				"error":             "invalid_request",
+				"error_description": "Token not provided",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/realms/default/protocol/openid-connect/token/introspect",
+				strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("X-Forwarded-Host", "example.com")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			resp, err := srv.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+			assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+			if tt.expectedCode == http.StatusUnauthorized {
+				assert.Equal(t, `Basic realm="default"`, resp.Header.Get("WWW-Authenticate"))
+			}
+
+			var response map[string]any
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			assert.Equal(t, tt.expectedResponse, response)
+		})
+	}
+}
+
+func TestIntrospectionRouteDisabled(t *testing.T) {
+	config.GetConfig().PathPrefix = ""
+
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(newDiscoveryTestProvider(t), server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/realms/default/protocol/openid-connect/token/introspect",
+		strings.NewReader("token=eyJ"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := srv.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+
+	// the introspection endpoint is only advertised if it is enabled
+	assert.Empty(t, server.NewDiscoveryInfo("https://example.com", "default", nil, false).IntrospectionEndpointURL)
+	discovery := server.NewDiscoveryInfo("https://example.com", "default", nil, true)
+	assert.Equal(t, "https://example.com/auth/realms/default/protocol/openid-connect/token/introspect",
+		discovery.IntrospectionEndpointURL)
+	assert.Equal(t, []string{"client_secret_basic", "client_secret_post"},
+		discovery.IntrospectionEndpointAuthMethodsSupported)
+}
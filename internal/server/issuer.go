@@ -0,0 +1,414 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"issuer-service-go/internal/config"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	headerForwarded       = "Forwarded"
+	headerForwardedHost   = "X-Forwarded-Host"
+	headerForwardedProto  = "X-Forwarded-Proto"
+	headerForwardedPort   = "X-Forwarded-Port"
+	headerForwardedPrefix = "X-Forwarded-Prefix"
+
+	defaultProto = "https"
+)
+
+var (
+	// ErrIssuerUnknown is returned in 'static' mode if no issuer URL is configured for the realm.
+	ErrIssuerUnknown = errors.New("no issuer URL is configured for the realm")
+	// ErrMissingForwardedHost is returned in 'header' mode if the request has no trusted forwarded host and no issuer
+	// URL is configured for the realm.
+	ErrMissingForwardedHost = errors.New("X-Forwarded-Host header must be set in the request")
+	// ErrInvalidForwardedHeader is returned if a forwarded header of a trusted proxy is malformed or not allowed.
+	ErrInvalidForwardedHeader = errors.New("invalid forwarded header")
+
+	hostPattern   = regexp.MustCompile(`^(\[[0-9a-fA-F:.]+\]|[a-zA-Z0-9.-]+)(:[0-9]{1,5})?$`)
+	portPattern   = regexp.MustCompile(`^[0-9]{1,5}$`)
+	prefixPattern = regexp.MustCompile(`^(/[a-zA-Z0-9._~-]+)*/?$`)
+)
+
+// IssuerResolver determines the base URL of the issuer of a realm. In 'static' mode the configured issuer URL of the
+// realm is used, so the advertised issuer does not depend on the request. In 'header' mode it is derived from the
+// configured forwarded headers ('X-Forwarded-Host', '-Proto', '-Port' and '-Prefix', or 'Forwarded' of RFC 7239),
+// which are only honored if the request was sent by a trusted proxy. Of these, the values set by the outermost
+// trusted proxy are used, so a client cannot choose them by sending the headers itself. If the request has no
+// trusted forwarded host, the configured issuer URL of the realm is used.
+type IssuerResolver struct {
+	mode             string
+	issuerURL        string
+	realmIssuerURLs  map[string]string
+	trustedProxies   []netip.Prefix
+	forwardedHeaders string
+	allowedHosts     []string
+}
+
+// NewIssuerResolver creates the resolver from the configured issuer URLs, trusted proxies and allowed hosts. If no
+// trusted proxy is configured in 'header' mode, the forwarded headers of every client are ignored, so an issuer URL
+// has to be configured instead. The allowed hosts are required in 'header' mode.
+func NewIssuerResolver(issuerConfig *config.IssuerConfig) (*IssuerResolver, error) {
+	resolver := &IssuerResolver{
+		mode:            issuerConfig.Mode,
+		realmIssuerURLs: make(map[string]string, len(issuerConfig.RealmIssuerURLs)),
+	}
+
+	var err error
+	if resolver.issuerURL, err = parseIssuerURL(issuerConfig.IssuerURL); err != nil {
+		return nil, fmt.Errorf("invalid ISSUER_URL: %w", err)
+	}
+	for realm, issuerURL := range issuerConfig.RealmIssuerURLs {
+		if resolver.realmIssuerURLs[realm], err = parseIssuerURL(issuerURL); err != nil {
+			return nil, fmt.Errorf("invalid issuer URL of realm %s: %w", realm, err)
+		}
+	}
+
+	switch issuerConfig.Mode {
+	case config.IssuerModeStatic:
+		if resolver.issuerURL == "" && len(resolver.realmIssuerURLs) == 0 {
+			return nil, errors.New("ISSUER_URL or REALM_ISSUER_URLS must be set, or ISSUER_MODE must be 'header'")
+		}
+		return resolver, nil
+	case config.IssuerModeHeader:
+	default:
+		return nil, fmt.Errorf("unknown issuer mode '%s'", issuerConfig.Mode)
+	}
+
+	for _, proxy := range issuerConfig.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %w", proxy, err)
+		}
+		resolver.trustedProxies = append(resolver.trustedProxies, prefix)
+	}
+	if len(resolver.trustedProxies) == 0 {
+		if resolver.issuerURL == "" && len(resolver.realmIssuerURLs) == 0 {
+			return nil, errors.New("TRUSTED_PROXIES, ISSUER_URL or REALM_ISSUER_URLS must be set in 'header' mode")
+		}
+		log.Warn().Msg("TRUSTED_PROXIES is not set, the forwarded headers are ignored and the issuer URLs are used")
+	}
+
+	switch issuerConfig.ForwardedHeaders {
+	case config.ForwardedHeadersXForwarded, config.ForwardedHeadersForwarded:
+		resolver.forwardedHeaders = issuerConfig.ForwardedHeaders
+	default:
+		return nil, fmt.Errorf("unknown forwarded headers '%s'", issuerConfig.ForwardedHeaders)
+	}
+
+	for _, host := range issuerConfig.AllowedHosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			resolver.allowedHosts = append(resolver.allowedHosts, host)
+		}
+	}
+	if len(resolver.allowedHosts) == 0 {
+		return nil, errors.New("ALLOWED_HOSTS must be set in 'header' mode")
+	}
+
+	return resolver, nil
+}
+
+// Resolve returns the base URL of the issuer of the realm, e.g. 'https://gateway.example.com/spacegate'. A nil
+// resolver has no issuer URLs.
+func (r *IssuerResolver) Resolve(c *fiber.Ctx, realm string) (string, error) {
+	if r == nil {
+		return "", fmt.Errorf("%w %s", ErrIssuerUnknown, realm)
+	}
+
+	staticURL := r.realmIssuerURLs[realm]
+	if staticURL == "" {
+		staticURL = r.issuerURL
+	}
+
+	if r.mode == config.IssuerModeStatic {
+		if staticURL == "" {
+			return "", fmt.Errorf("%w %s", ErrIssuerUnknown, realm)
+		}
+		return staticURL + config.GetConfig().PathPrefix, nil
+	}
+
+	var forwarded forwardedValues
+	if r.isTrusted(c) {
+		forwarded = r.readForwarded(c)
+	} else {
+		log.Debug().Msgf("ignoring forwarded headers of untrusted client %s", c.Context().RemoteIP())
+	}
+
+	if forwarded.host == "" {
+		if staticURL == "" {
+			return "", ErrMissingForwardedHost
+		}
+		return staticURL + config.GetConfig().PathPrefix, nil
+	}
+
+	return r.buildURL(forwarded)
+}
+
+// vary adds the forwarded headers to the Vary header in 'header' mode, because the resolved issuer URL depends on
+// them, so a shared cache does not serve the response of one host to another.
+func (r *IssuerResolver) vary(c *fiber.Ctx) {
+	if r == nil || r.mode != config.IssuerModeHeader || len(r.trustedProxies) == 0 {
+		return
+	}
+	if r.forwardedHeaders == config.ForwardedHeadersForwarded {
+		c.Vary(headerForwarded)
+		return
+	}
+	c.Vary(fiber.HeaderXForwardedFor, headerForwardedHost, headerForwardedProto, headerForwardedPort,
+		headerForwardedPrefix)
+}
+
+// isTrusted returns whether the request was sent by a trusted proxy.
+func (r *IssuerResolver) isTrusted(c *fiber.Ctx) bool {
+	remoteAddr, ok := netip.AddrFromSlice(c.Context().RemoteIP())
+	if !ok {
+		return false
+	}
+	return r.isTrustedAddr(remoteAddr.Unmap())
+}
+
+// isTrustedAddr returns whether the address belongs to a trusted proxy. An invalid address is never trusted.
+func (r *IssuerResolver) isTrustedAddr(addr netip.Addr) bool {
+	return slices.ContainsFunc(r.trustedProxies, func(prefix netip.Prefix) bool {
+		return prefix.Contains(addr)
+	})
+}
+
+// readForwarded returns the values set by the outermost trusted proxy in the configured forwarded headers. The
+// headers of the other kind are ignored, so a client cannot send them next to the ones set by the proxies.
+// 'Forwarded' has no equivalent of 'X-Forwarded-Prefix', so PATH_PREFIX is used with it.
+func (r *IssuerResolver) readForwarded(c *fiber.Ctx) forwardedValues {
+	if r.forwardedHeaders == config.ForwardedHeadersForwarded {
+		element := r.forwardedElement(parseForwarded(c.Get(headerForwarded)))
+		return forwardedValues{host: element["host"], proto: element["proto"]}
+	}
+
+	hops := r.trustedHops(splitValues(c.Get(fiber.HeaderXForwardedFor)))
+	return forwardedValues{
+		host:   hopValue(c.Get(headerForwardedHost), hops),
+		proto:  hopValue(c.Get(headerForwardedProto), hops),
+		port:   hopValue(c.Get(headerForwardedPort), hops),
+		prefix: hopValue(c.Get(headerForwardedPrefix), hops),
+	}
+}
+
+// trustedHops returns the number of trusted proxies the request passed, counted from the remote address, which is
+// a trusted proxy, until the first untrusted node. The nodes are ordered from the client to the proxy closest to
+// the service, like the addresses of 'X-Forwarded-For' or the 'for' parameters of 'Forwarded'.
+func (r *IssuerResolver) trustedHops(nodes []string) int {
+	hops := 1
+	for i := len(nodes) - 1; i >= 0 && r.isTrustedAddr(parseNodeAddr(nodes[i])); i-- {
+		hops++
+	}
+	return hops
+}
+
+// forwardedElement returns the element of a 'Forwarded' header that was added by the outermost trusted proxy, or
+// nil if not every trusted proxy added an element, because the remaining elements may have been sent by the client.
+func (r *IssuerResolver) forwardedElement(elements []map[string]string) map[string]string {
+	nodes := make([]string, len(elements))
+	for i, element := range elements {
+		nodes[i] = element["for"]
+	}
+
+	hops := r.trustedHops(nodes)
+	if len(elements) < hops {
+		return nil
+	}
+	return elements[len(elements)-hops]
+}
+
+// buildURL validates the forwarded values and builds the issuer URL from them.
+func (r *IssuerResolver) buildURL(forwarded forwardedValues) (string, error) {
+	host := strings.ToLower(forwarded.host)
+	hostMatch := hostPattern.FindStringSubmatch(host)
+	if hostMatch == nil {
+		return "", fmt.Errorf("%w: host '%s'", ErrInvalidForwardedHeader, forwarded.host)
+	}
+	if !r.isAllowed(hostMatch[1]) {
+		return "", fmt.Errorf("%w: host '%s' is not allowed", ErrInvalidForwardedHeader, forwarded.host)
+	}
+
+	proto := strings.ToLower(forwarded.proto)
+	switch proto {
+	case "":
+		proto = defaultProto
+	case "http", "https":
+	default:
+		return "", fmt.Errorf("%w: proto '%s'", ErrInvalidForwardedHeader, forwarded.proto)
+	}
+
+	if forwarded.port != "" {
+		if !portPattern.MatchString(forwarded.port) {
+			return "", fmt.Errorf("%w: port '%s'", ErrInvalidForwardedHeader, forwarded.port)
+		}
+		// the port is only appended if the host has none and it is not the default port of the protocol
+		isDefaultPort := (proto == "https" && forwarded.port == "443") || (proto == "http" && forwarded.port == "80")
+		if hostMatch[2] == "" && !isDefaultPort {
+			host += ":" + forwarded.port
+		}
+	}
+
+	prefix := config.GetConfig().PathPrefix
+	if forwarded.prefix != "" {
+		if !prefixPattern.MatchString(forwarded.prefix) {
+			return "", fmt.Errorf("%w: prefix '%s'", ErrInvalidForwardedHeader, forwarded.prefix)
+		}
+		prefix = strings.TrimSuffix(forwarded.prefix, "/")
+	}
+
+	return proto + "://" + host + prefix, nil
+}
+
+// isAllowed returns whether the hostname is allowed. Entries starting with '*.' allow every subdomain.
+func (r *IssuerResolver) isAllowed(hostname string) bool {
+	return slices.ContainsFunc(r.allowedHosts, func(allowedHost string) bool {
+		if suffix, isWildcard := strings.CutPrefix(allowedHost, "*"); isWildcard {
+			return strings.HasSuffix(hostname, suffix) && len(hostname) > len(suffix)
+		}
+		return hostname == allowedHost
+	})
+}
+
+type forwardedValues struct {
+	host   string
+	proto  string
+	port   string
+	prefix string
+}
+
+// hopValue returns the value of a comma-separated header that was appended by the outermost of the given number of
+// trusted proxies. If the header has fewer values than trusted proxies, not every proxy appended a value, so the
+// values may have been sent by the client and none is returned.
+func hopValue(header string, hops int) string {
+	values := splitValues(header)
+	if len(values) < hops {
+		return ""
+	}
+	return values[len(values)-hops]
+}
+
+// splitValues returns the trimmed values of a comma-separated header.
+func splitValues(header string) []string {
+	if strings.TrimSpace(header) == "" {
+		return nil
+	}
+	values := strings.Split(header, ",")
+	for i, value := range values {
+		values[i] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// parseNodeAddr returns the IP address of a node of 'X-Forwarded-For' or of the 'for' parameter of 'Forwarded'
+// (RFC 7239 section 6), e.g. '192.0.2.43', '192.0.2.43:47011' or '[2001:db8::17]:4711'. Obfuscated and unknown
+// nodes return an invalid address.
+func parseNodeAddr(node string) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(node); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(node, "["), "]"))
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// parseForwarded returns the parameters of every element of a 'Forwarded' header (RFC 7239 section 4), from the
+// first proxy to the last one. Parameter names are lowercased and quoted values are unquoted.
+func parseForwarded(header string) []map[string]string {
+	if strings.TrimSpace(header) == "" {
+		return nil
+	}
+
+	var elements []map[string]string
+	params := make(map[string]string)
+	var name, value strings.Builder
+	inValue, inQuotes, escaped := false, false, false
+
+	addParam := func() {
+		if key := strings.ToLower(strings.TrimSpace(name.String())); key != "" {
+			params[key] = strings.TrimSpace(value.String())
+		}
+		name.Reset()
+		value.Reset()
+		inValue = false
+	}
+
+	for _, char := range header {
+		switch {
+		case escaped:
+			value.WriteRune(char)
+			escaped = false
+		case inQuotes && char == '\\':
+			escaped = true
+		case char == '"' && inValue:
+			inQuotes = !inQuotes
+		case inQuotes:
+			value.WriteRune(char)
+		case char == ',':
+			addParam()
+			elements = append(elements, params)
+			params = make(map[string]string)
+		case char == ';':
+			addParam()
+		case char == '=' && !inValue:
+			inValue = true
+		case inValue:
+			value.WriteRune(char)
+		default:
+			name.WriteRune(char)
+		}
+	}
+	addParam()
+	return append(elements, params)
+}
+
+// parseIssuerURL validates a configured issuer URL and returns it without trailing slash.
+func parseIssuerURL(issuerURL string) (string, error) {
+	if issuerURL == "" {
+		return "", nil
+	}
+
+	parsedURL, err := url.Parse(issuerURL)
+	if err != nil {
+		return "", err
+	}
+	if (parsedURL.Scheme != "https" && parsedURL.Scheme != "http") || parsedURL.Host == "" {
+		return "", fmt.Errorf("'%s' is not an absolute HTTP(S) URL", issuerURL)
+	}
+	if parsedURL.RawQuery != "" || parsedURL.Fragment != "" {
+		return "", fmt.Errorf("'%s' must not contain a query or fragment", issuerURL)
+	}
+	return strings.TrimSuffix(issuerURL, "/"), nil
+}
+
+// parsePrefix parses a CIDR or a single IP address.
+func parsePrefix(proxy string) (netip.Prefix, error) {
+	if strings.Contains(proxy, "/") {
+		prefix, err := netip.ParsePrefix(proxy)
+		return prefix.Masked(), err
+	}
+
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
@@ -0,0 +1,428 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"encoding/json"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/realm"
+	"issuer-service-go/internal/server"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRemoteIP is the remote address of requests sent with fiber.App.Test.
+const testRemoteIP = "0.0.0.0"
+
+// newTestIssuerResolver creates a resolver in 'header' mode that trusts the X-Forwarded-* headers of test requests.
+func newTestIssuerResolver(t *testing.T) *server.IssuerResolver {
+	t.Helper()
+
+	issuerResolver, err := server.NewIssuerResolver(&config.IssuerConfig{
+		Mode:             config.IssuerModeHeader,
+		TrustedProxies:   []string{testRemoteIP},
+		ForwardedHeaders: config.ForwardedHeadersXForwarded,
+		AllowedHosts:     []string{"localhost", "example.com", "*.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create issuer resolver: %v", err)
+	}
+	return issuerResolver
+}
+
+// header returns the configuration in 'header' mode with the X-Forwarded-* headers, unless other forwarded headers
+// are set, and every subdomain of example.com allowed, unless other hosts are set.
+func header(issuerConfig config.IssuerConfig) config.IssuerConfig {
+	issuerConfig.Mode = config.IssuerModeHeader
+	if issuerConfig.ForwardedHeaders == "" {
+		issuerConfig.ForwardedHeaders = config.ForwardedHeadersXForwarded
+	}
+	if issuerConfig.AllowedHosts == nil {
+		issuerConfig.AllowedHosts = []string{"example.com", "*.example.com"}
+	}
+	return issuerConfig
+}
+
+func TestNewIssuerResolver(t *testing.T) {
+	tests := []struct {
+		name         string
+		issuerConfig config.IssuerConfig
+		err          bool
+	}{
+		{
+			name:         "static issuer URL",
+			issuerConfig: config.IssuerConfig{Mode: config.IssuerModeStatic, IssuerURL: "https://issuer.example.com"},
+		},
+		{
+			name: "static issuer URLs of realms only",
+			issuerConfig: config.IssuerConfig{
+				Mode:            config.IssuerModeStatic,
+				RealmIssuerURLs: map[string]string{"default": "http://issuer.internal:8080/"},
+			},
+		},
+		{
+			name:         "static mode without issuer URL",
+			issuerConfig: config.IssuerConfig{Mode: config.IssuerModeStatic},
+			err:          true,
+		},
+		{
+			name:         "issuer URL without scheme",
+			issuerConfig: config.IssuerConfig{Mode: config.IssuerModeStatic, IssuerURL: "issuer.example.com"},
+			err:          true,
+		},
+		{
+			name: "issuer URL of a realm with query",
+			issuerConfig: config.IssuerConfig{
+				Mode:            config.IssuerModeStatic,
+				RealmIssuerURLs: map[string]string{"default": "https://issuer.example.com?realm=default"},
+			},
+			err: true,
+		},
+		{
+			name:         "unknown mode",
+			issuerConfig: config.IssuerConfig{Mode: "dynamic", IssuerURL: "https://issuer.example.com"},
+			err:          true,
+		},
+		{
+			name:         "header mode without trusted proxies and issuer URL",
+			issuerConfig: header(config.IssuerConfig{}),
+			err:          true,
+		},
+		{
+			name:         "header mode without trusted proxies",
+			issuerConfig: header(config.IssuerConfig{IssuerURL: "https://issuer.example.com"}),
+		},
+		{
+			name: "header mode with IP addresses and CIDRs",
+			issuerConfig: header(config.IssuerConfig{
+				TrustedProxies: []string{"10.0.0.1", "10.1.0.0/16", "fd00::/8", " "},
+			}),
+		},
+		{
+			name: "header mode with Forwarded header",
+			issuerConfig: header(config.IssuerConfig{
+				TrustedProxies:   []string{"10.0.0.1"},
+				ForwardedHeaders: config.ForwardedHeadersForwarded,
+			}),
+		},
+		{
+			name: "header mode with unknown forwarded headers",
+			issuerConfig: header(config.IssuerConfig{
+				TrustedProxies:   []string{"10.0.0.1"},
+				ForwardedHeaders: "x-forwarded-host",
+			}),
+			err: true,
+		},
+		{
+			name: "header mode without allowed hosts",
+			issuerConfig: config.IssuerConfig{
+				Mode:             config.IssuerModeHeader,
+				TrustedProxies:   []string{"10.0.0.1"},
+				ForwardedHeaders: config.ForwardedHeadersXForwarded,
+				AllowedHosts:     []string{" "},
+			},
+			err: true,
+		},
+		{
+			name:         "invalid IP address",
+			issuerConfig: header(config.IssuerConfig{TrustedProxies: []string{"10.0.0.256"}}),
+			err:          true,
+		},
+		{
+			name:         "invalid CIDR",
+			issuerConfig: header(config.IssuerConfig{TrustedProxies: []string{"10.0.0.0/33"}}),
+			err:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.NewIssuerResolver(&tt.issuerConfig)
+			assert.Equalf(t, tt.err, err != nil, "expected error: %v, got: %v", tt.err, err)
+		})
+	}
+}
+
+func TestIssuerResolution(t *testing.T) {
+	config.GetConfig().PathPrefix = "/spacegate"
+	t.Cleanup(func() { config.GetConfig().PathPrefix = "" })
+
+	static := config.IssuerConfig{
+		Mode:            config.IssuerModeStatic,
+		IssuerURL:       "https://issuer.example.com",
+		RealmIssuerURLs: map[string]string{"internal": "http://issuer.internal:8080"},
+	}
+	staticOfOtherRealm := config.IssuerConfig{
+		Mode:            config.IssuerModeStatic,
+		RealmIssuerURLs: map[string]string{"internal": "http://issuer.internal:8080"},
+	}
+	withoutProxies := header(config.IssuerConfig{IssuerURL: "https://issuer.example.com"})
+	trusted := header(config.IssuerConfig{TrustedProxies: []string{testRemoteIP}})
+	trustedChain := trusted
+	trustedChain.TrustedProxies = []string{testRemoteIP, "10.0.0.0/8"}
+	trustedForwarded := trusted
+	trustedForwarded.ForwardedHeaders = config.ForwardedHeadersForwarded
+	trustedForwardedChain := trustedChain
+	trustedForwardedChain.ForwardedHeaders = config.ForwardedHeadersForwarded
+	untrusted := header(config.IssuerConfig{TrustedProxies: []string{"10.0.0.0/8"}})
+	untrustedWithIssuerURL := untrusted
+	untrustedWithIssuerURL.IssuerURL = "https://issuer.example.com/"
+	trustedWithIssuerURL := trusted
+	trustedWithIssuerURL.IssuerURL = "https://issuer.example.com"
+	trustedWithAllowlist := trusted
+	trustedWithAllowlist.AllowedHosts = []string{"*.example.com"}
+
+	tests := []struct {
+		description    string
+		issuerConfig   config.IssuerConfig
+		realm          string
+		headers        map[string]string
+		expectedCode   int
+		expectedIssuer string
+	}{
+		{
+			description:    "static issuer URL ignores forwarded headers",
+			issuerConfig:   static,
+			headers:        map[string]string{"X-Forwarded-Host": "example.com"},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "https://issuer.example.com/spacegate/auth/realms/default",
+		},
+		{
+			description:    "static issuer URL of the realm",
+			issuerConfig:   static,
+			realm:          "internal",
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "http://issuer.internal:8080/spacegate/auth/realms/internal",
+		},
+		{
+			description:  "no static issuer URL for the realm",
+			issuerConfig: staticOfOtherRealm,
+			headers:      map[string]string{"X-Forwarded-Host": "example.com"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:    "forwarded headers are ignored if no trusted proxy is configured",
+			issuerConfig:   withoutProxies,
+			headers:        map[string]string{"X-Forwarded-Host": "example.com"},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "https://issuer.example.com/spacegate/auth/realms/default",
+		},
+		{
+			description:  "X-Forwarded-* values appended by a trusted proxy",
+			issuerConfig: trusted,
+			headers: map[string]string{
+				"X-Forwarded-For":    "192.0.2.60",
+				"X-Forwarded-Host":   "attacker.example.net, example.com",
+				"X-Forwarded-Proto":  "https, http",
+				"X-Forwarded-Port":   "8080",
+				"X-Forwarded-Prefix": "/gateway/",
+			},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "http://example.com:8080/gateway/auth/realms/default",
+		},
+		{
+			description:  "X-Forwarded-* values of the outermost trusted proxy",
+			issuerConfig: trustedChain,
+			headers: map[string]string{
+				"X-Forwarded-For":  "10.0.0.2, 192.0.2.60, 10.0.0.1",
+				"X-Forwarded-Host": "attacker.example.net, example.com, proxy.internal",
+			},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "https://example.com/spacegate/auth/realms/default",
+		},
+		{
+			description:  "default port is not appended",
+			issuerConfig: trusted,
+			headers: map[string]string{
+				"X-Forwarded-Host":  "example.com",
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Port":  "443",
+			},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "https://example.com/spacegate/auth/realms/default",
+		},
+		{
+			description:  "X-Forwarded-* values of a trusted proxy that did not append the values of every hop",
+			issuerConfig: trustedChain,
+			headers: map[string]string{
+				"X-Forwarded-For":  "192.0.2.60, 10.0.0.1",
+				"X-Forwarded-Host": "example.com",
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "Forwarded header sent next to trusted X-Forwarded-* headers is ignored",
+			issuerConfig: trusted,
+			headers: map[string]string{
+				"Forwarded":        "host=evil.example.com",
+				"X-Forwarded-For":  "192.0.2.60",
+				"X-Forwarded-Host": "gateway.example.com",
+			},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "https://gateway.example.com/spacegate/auth/realms/default",
+		},
+		{
+			description:  "X-Forwarded-Prefix sent by the client is ignored",
+			issuerConfig: trustedChain,
+			headers: map[string]string{
+				"X-Forwarded-For":    "192.0.2.60, 10.0.0.1",
+				"X-Forwarded-Host":   "example.com, proxy.example.com",
+				"X-Forwarded-Prefix": "/evil",
+			},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "https://example.com/spacegate/auth/realms/default",
+		},
+		{
+			description:  "X-Forwarded-Port sent by the client is ignored",
+			issuerConfig: trustedChain,
+			headers: map[string]string{
+				"X-Forwarded-For":  "192.0.2.60, 10.0.0.1",
+				"X-Forwarded-Host": "example.com, proxy.example.com",
+				"X-Forwarded-Port": "8443",
+			},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "https://example.com/spacegate/auth/realms/default",
+		},
+		{
+			description:  "Forwarded header of a trusted proxy",
+			issuerConfig: trustedForwarded,
+			headers: map[string]string{
+				"Forwarded":          `for=192.0.2.60;host=attacker.example.net, for=192.0.2.61;proto=http;host="example.com:8080"`,
+				"X-Forwarded-Host":   "other.example.com",
+				"X-Forwarded-Prefix": "/evil",
+			},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "http://example.com:8080/spacegate/auth/realms/default",
+		},
+		{
+			description:  "X-Forwarded-* headers are ignored if the Forwarded header is trusted",
+			issuerConfig: trustedForwarded,
+			headers: map[string]string{
+				"X-Forwarded-Host": "example.com",
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "Forwarded header of a trusted proxy that did not add an element for every hop",
+			issuerConfig: trustedForwardedChain,
+			headers: map[string]string{
+				"Forwarded": `for="10.0.0.1:8080";host=example.com`,
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "Forwarded element of the outermost trusted proxy",
+			issuerConfig: trustedForwardedChain,
+			headers: map[string]string{
+				"Forwarded": `for=192.0.2.60;host=attacker.example.net, for="[2001:db8::17]:4711";host=example.com, ` +
+					`for="10.0.0.1:8080";host=proxy.internal`,
+			},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "https://example.com/spacegate/auth/realms/default",
+		},
+		{
+			description:    "headers of an untrusted client are ignored and ISSUER_URL is used",
+			issuerConfig:   untrustedWithIssuerURL,
+			headers:        map[string]string{"X-Forwarded-Host": "attacker.example.net"},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "https://issuer.example.com/spacegate/auth/realms/default",
+		},
+		{
+			description:    "ISSUER_URL if no forwarded host is sent",
+			issuerConfig:   trustedWithIssuerURL,
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "https://issuer.example.com/spacegate/auth/realms/default",
+		},
+		{
+			description:  "headers of an untrusted client without ISSUER_URL",
+			issuerConfig: untrusted,
+			headers:      map[string]string{"X-Forwarded-Host": "example.com"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "no forwarded host without ISSUER_URL",
+			issuerConfig: trusted,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:    "host matches a wildcard of the allowlist",
+			issuerConfig:   trustedWithAllowlist,
+			headers:        map[string]string{"X-Forwarded-Host": "Gateway.Example.com:8443"},
+			expectedCode:   http.StatusOK,
+			expectedIssuer: "https://gateway.example.com:8443/spacegate/auth/realms/default",
+		},
+		{
+			description:  "host is not allowed",
+			issuerConfig: trustedWithAllowlist,
+			headers:      map[string]string{"X-Forwarded-Host": "example.com.attacker.example.net"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "invalid host",
+			issuerConfig: trusted,
+			headers:      map[string]string{"X-Forwarded-Host": "example.com/path?query"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "invalid proto",
+			issuerConfig: trusted,
+			headers:      map[string]string{"X-Forwarded-Host": "example.com", "X-Forwarded-Proto": "javascript"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "invalid prefix",
+			issuerConfig: trusted,
+			headers: map[string]string{
+				"X-Forwarded-Host":   "example.com",
+				"X-Forwarded-Prefix": "//attacker.example.net",
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			issuerResolver, err := server.NewIssuerResolver(&tt.issuerConfig)
+			if err != nil {
+				t.Fatalf("failed to create issuer resolver: %v", err)
+			}
+
+			srv := server.New()
+			srv.RegisterRoutes(server.NewHandler(newDiscoveryTestProvider(t), server.HandlerOptions{
+				RealmRegistry:  realm.NewWildcardRegistry(),
+				IssuerResolver: issuerResolver,
+			}))
+
+			realmName := tt.realm
+			if realmName == "" {
+				realmName = "default"
+			}
+			route := "/auth/realms/" + realmName + "/.well-known/openid-configuration"
+			req := httptest.NewRequest(http.MethodGet, route, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			resp, err := srv.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var discovery server.Discovery
+			if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			assert.Equal(t, tt.expectedIssuer, discovery.IssuerURL)
+			assert.Equal(t, tt.expectedIssuer+"/protocol/openid-connect/certs", discovery.JwksURL)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"issuer-service-go/internal/metrics"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+const (
+	// unmatchedRoute is used as route label for requests that did not match any route, so unknown paths
+	// do not create new time series.
+	unmatchedRoute = "unmatched"
+	// unknownRealm is used as realm label for realms that are not configured in the realm registry, so clients
+	// cannot create new time series by requesting arbitrary realms.
+	unknownRealm = "unknown"
+)
+
+// healthPaths are the paths of the health endpoints, which are served without client certificate.
+var healthPaths = []string{"/health", "/health/live", "/health/ready", "/health/startup"}
+
+// metricsMiddleware records the count and latency of the requests per route template and realm.
+func (s *FiberServer) metricsMiddleware(c *fiber.Ctx) error {
+	start := time.Now()
+	middlewareRoute := c.Route()
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		status = fiberErr.Code
+	} else if err != nil {
+		status = fiber.StatusInternalServerError
+	}
+
+	route := c.Route().Path
+	if c.Route() == middlewareRoute {
+		route = unmatchedRoute
+	}
+
+	metrics.ObserveRequest(route, s.realmLabel(c.Params("realm")), utils.CopyString(c.Method()), status,
+		time.Since(start))
+	return err
+}
+
+// clientCertificateMiddleware rejects requests without verified client certificate if client certificates are
+// required. The health endpoints are exempt, because the probes of the kubelet send no client certificate.
+func (s *FiberServer) clientCertificateMiddleware(c *fiber.Ctx) error {
+	if !s.requireClientCert || slices.Contains(healthPaths, c.Path()) {
+		return c.Next()
+	}
+
+	if tlsState := c.Context().TLSConnectionState(); tlsState != nil && len(tlsState.VerifiedChains) > 0 {
+		return c.Next()
+	}
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Error{
+		Code:    fiber.StatusForbidden,
+		Message: "a trusted client certificate is required",
+	})
+}
+
+// realmLabel returns the realm if it is configured explicitly, unknownRealm otherwise, or an empty string if the
+// route has no realm.
+func (s *FiberServer) realmLabel(realm string) string {
+	if realm == "" {
+		return ""
+	}
+	if s.realmRegistry == nil || !s.realmRegistry.Contains(realm) {
+		return unknownRealm
+	}
+	// the parameters reference the request buffer, which is reused after the request
+	return utils.CopyString(realm)
+}
@@ -6,27 +6,57 @@ package server
 
 import (
 	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/metrics"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	wellKnownAuthorizationServer = "/.well-known/oauth-authorization-server"
+)
+
 func (s *FiberServer) RegisterRoutes(handler *Handler) {
+	s.realmRegistry = handler.realmRegistry
+
 	s.App.Get("/health", func(c *fiber.Ctx) error {
 		return c.SendString("OK")
 	})
+	s.App.Get("/health/live", handler.LivenessHandler)
+	s.App.Get("/health/ready", handler.ReadinessHandler)
+	s.App.Get("/health/startup", handler.StartupHandler)
+	s.App.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
 
 	v1 := s.App.Group(config.GetConfig().ServerConfig.BasePath)
 	v1.Get("/auth/*", notImplemented)
 	v1.Get("/discovery/:realm", handler.DiscoveryHandler)
 	v1.Get("/certs/:realm", handler.JwksHandler)
 	v1.Get("/issuer/:realm", handler.IssuerHandler)
+	v1.Post("/validate/:realm", handler.ValidationHandler)
 
 	auth := s.App.Group("/auth/realms/:realm")
 	auth.Get("/protocol/openid-connect/auth/*", notImplemented)
 	auth.Get("/.well-known/openid-configuration", handler.DiscoveryHandler)
 	auth.Get("/protocol/openid-connect/certs", handler.JwksHandler)
+	auth.Post("/protocol/openid-connect/token/introspect", handler.IntrospectionHandler)
 	auth.Get("/", handler.IssuerHandler)
+
+	// RFC 8414 inserts the well-known path between the host and the path of the issuer
+	for _, issuerPath := range authorizationServerIssuerPaths(config.GetConfig().PathPrefix) {
+		s.App.Get(wellKnownAuthorizationServer+issuerPath, handler.DiscoveryHandler)
+	}
+}
+
+// authorizationServerIssuerPaths returns the issuer paths under which the authorization server metadata is
+// served. If a path prefix is configured, the metadata is also served under the prefixed issuer path, because
+// a proxy may forward the well-known path without removing the prefix.
+func authorizationServerIssuerPaths(pathPrefix string) []string {
+	issuerPaths := []string{"/auth/realms/:realm"}
+	if pathPrefix != "" {
+		issuerPaths = append(issuerPaths, pathPrefix+"/auth/realms/:realm")
+	}
+	return issuerPaths
 }
 
 func notImplemented(c *fiber.Ctx) error {
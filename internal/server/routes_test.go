@@ -10,6 +10,7 @@ import (
 	"io"
 	"issuer-service-go/internal/config"
 	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/realm"
 	"issuer-service-go/internal/server"
 	"net/http"
 	"net/http/httptest"
@@ -18,6 +19,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMain(m *testing.M) {
@@ -71,7 +73,10 @@ func TestHealthRoute(t *testing.T) {
 	}
 
 	srv := server.New()
-	handler := server.NewHandler(nil) // jwksProvider is not needed for that test
+	// jwksProvider is not needed for that test
+	handler := server.NewHandler(nil, server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	})
 	srv.RegisterRoutes(handler)
 
 	for _, tt := range tests {
@@ -122,7 +127,10 @@ func TestAuthRoute(t *testing.T) {
 	}
 
 	srv := server.New()
-	handler := server.NewHandler(nil) // jwksProvider is not needed for that test
+	// jwksProvider is not needed for that test
+	handler := server.NewHandler(nil, server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	})
 	srv.RegisterRoutes(handler)
 
 	for _, tt := range tests {
@@ -240,8 +248,23 @@ func TestDiscoveryRoute(t *testing.T) {
 		},
 	}
 
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./router_testdata/",
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+
 	srv := server.New()
-	handler := server.NewHandler(nil) // jwksProvider is not needed for that test
+	jwksProvider, _ := jwks.NewFileProvider(t.Context(), jwksConfig)
+	handler := server.NewHandler(jwksProvider, server.HandlerOptions{
+		RealmRegistry:  realm.NewWildcardRegistry(),
+		IssuerResolver: newTestIssuerResolver(t),
+	})
 	srv.RegisterRoutes(handler)
 
 	for _, tt := range tests {
@@ -264,6 +287,42 @@ func TestDiscoveryRoute(t *testing.T) {
 	}
 }
 
+func TestDiscoveryRouteSigningAlgs(t *testing.T) {
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./router_testdata/",
+		CertFileNameNext:   "ec-tls.crt",
+		KidFileNameNext:    "ec-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+
+	srv := server.New()
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	handler := server.NewHandler(jwksProvider, server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	})
+	srv.RegisterRoutes(handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/realms/default/.well-known/openid-configuration", nil)
+	req.Header.Set("X-Forwarded-Host", "localhost:8080")
+	resp, _ := srv.Test(req, 5)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var actualResponse server.Discovery
+	err = json.NewDecoder(resp.Body).Decode(&actualResponse)
+	if err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+
+	assert.Equal(t, []string{"ES256", "RS256"}, actualResponse.IDTokenSigningAlgValuesSupported)
+}
+
 func TestJwksRoute(t *testing.T) {
 	jwkPrev := jwks.Jwk{
 		Kid: "5A9C11C2-A370-473D-AB2B-4B8BC247724C",
@@ -343,8 +402,10 @@ func TestJwksRoute(t *testing.T) {
 	}
 
 	srv := server.New()
-	jwksProvider, _ := jwks.NewFileProvider(jwksConfig)
-	handler := server.NewHandler(jwksProvider)
+	jwksProvider, _ := jwks.NewFileProvider(t.Context(), jwksConfig)
+	handler := server.NewHandler(jwksProvider, server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	})
 	srv.RegisterRoutes(handler)
 
 	for _, tt := range tests {
@@ -370,14 +431,21 @@ func TestJwksRoute(t *testing.T) {
 }
 
 func TestDefaultRealmRoute(t *testing.T) {
+	config.GetConfig().PathPrefix = ""
+
 	defaultRealm := jwks.DefaultRealm{
 		Realm:     "default",
 		PublicKey: "MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAy0skyMX46fzroq2Ma2pr1iP+Rt+x3IKufm6rf54vwcq/jxYPBajNREM0dtKfjj1p590Gme1+QQW3uS03eK5Rp5CNGGonFrzWsqlYa3dYgHpcZ6UgFGBPJvJCqBnEFP3d7zdg4GKOGDGv+KEM49bKm1qfIvxJ+JpATzv06vNptsGrtygol1rVbWkq8cFZ5mIzSe3Jk0vx8tw3rEint4uG8OHNWqfdHBKblTVjuW2w6cYr7gk6ujm9FswjkZ5us0mgBekw0prLK5bYwNzHERdFtvaCvOIwNZvqwsETQFpQFBwB/7kdEFfuSHbDeG0Mg5/aIikKom2TV+bEy21V6Sw/1QIDAQAB",
 	}
 
+	keycloakRealm := defaultRealm
+	keycloakRealm.TokenService = "https://example.com/auth/realms/default/protocol/openid-connect"
+	keycloakRealm.AccountService = "https://example.com/auth/realms/default/account"
+
 	tests := []struct {
 		description          string
 		route                string
+		host                 string
 		expectedCode         int
 		expectedDefaultRealm jwks.DefaultRealm
 	}{
@@ -393,6 +461,13 @@ func TestDefaultRealmRoute(t *testing.T) {
 			expectedCode:         200,
 			expectedDefaultRealm: defaultRealm,
 		},
+		{
+			description:          "Test /auth/realms/:realm endpoint with the service URLs of the issuer",
+			route:                "/auth/realms/default",
+			host:                 "example.com",
+			expectedCode:         200,
+			expectedDefaultRealm: keycloakRealm,
+		},
 	}
 
 	jwksConfig := &config.JwksFileConfig{
@@ -407,13 +482,18 @@ func TestDefaultRealmRoute(t *testing.T) {
 	}
 
 	srv := server.New()
-	jwksProvider, _ := jwks.NewFileProvider(jwksConfig)
-	handler := server.NewHandler(jwksProvider)
+	jwksProvider, _ := jwks.NewFileProvider(t.Context(), jwksConfig)
+	handler := server.NewHandler(jwksProvider, server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	})
 	srv.RegisterRoutes(handler)
 
 	for _, tt := range tests {
 		t.Run(tt.description, func(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, tt.route, nil)
+			if tt.host != "" {
+				req.Header.Set("X-Forwarded-Host", tt.host)
+			}
 			resp, _ := srv.Test(req, 5)
 			assert.Equalf(t, tt.expectedCode, resp.StatusCode, tt.description)
 
@@ -421,6 +501,7 @@ func TestDefaultRealmRoute(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to read response body: %v", err)
 			}
+			assert.Contains(t, string(bodyBytes), `"tokens-not-before":0`)
 
 			var defaultR jwks.DefaultRealm
 			err = json.Unmarshal(bodyBytes, &defaultR)
@@ -432,3 +513,480 @@ func TestDefaultRealmRoute(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricsRoute(t *testing.T) {
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./router_testdata/",
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+
+	srv := server.New()
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	realmRegistry, err := realm.NewRegistry(&config.RealmConfig{Realms: []string{"metrics-realm", "*"}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create realm registry: %v", err)
+	}
+	handler := server.NewHandler(jwksProvider, server.HandlerOptions{
+		RealmRegistry:  realmRegistry,
+		IssuerResolver: newTestIssuerResolver(t),
+	})
+	srv.RegisterRoutes(handler)
+
+	for _, route := range []string{
+		"/auth/realms/metrics-realm/protocol/openid-connect/certs",
+		"/auth/realms/random-realm-4711/protocol/openid-connect/certs",
+		"/unknown/route",
+	} {
+		resp, err := srv.Test(httptest.NewRequest(http.MethodGet, route, nil), -1)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	resp, err := srv.Test(httptest.NewRequest(http.MethodGet, "/metrics", nil), -1)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	body := string(bodyBytes)
+
+	assert.Contains(t, body, `issuer_service_http_requests_total{method="GET",realm="metrics-realm",`+
+		`route="/auth/realms/:realm/protocol/openid-connect/certs",status="200"}`)
+	// realms that are not configured explicitly do not create new time series
+	assert.Contains(t, body, `issuer_service_http_requests_total{method="GET",realm="unknown",`+
+		`route="/auth/realms/:realm/protocol/openid-connect/certs",status="200"}`)
+	assert.NotContains(t, body, "random-realm-4711")
+	assert.Contains(t, body, `issuer_service_http_requests_total{method="GET",realm="",route="unmatched",status="404"}`)
+	assert.Contains(t, body, `issuer_service_jwks_keys{provider="file",realm="",slot="active"} 1`)
+	assert.Contains(t, body, `issuer_service_jwks_certificate_expiry_seconds{`+
+		`kid="F7959F8A-EC16-44BC-9F77-2A6F9580BDB4",provider="file",realm=""}`)
+	assert.Contains(t, body, `issuer_service_jwks_reloads_total{provider="file",realm="",result="success"}`)
+	assert.Contains(t, body, `issuer_service_jwks_seconds_since_last_successful_reload{provider="file",realm=""}`)
+}
+
+func TestJwksRouteCaching(t *testing.T) {
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./router_testdata/",
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+
+	srv := server.New()
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	handler := server.NewHandler(jwksProvider, server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	})
+	srv.RegisterRoutes(handler)
+
+	route := "/auth/realms/default/protocol/openid-connect/certs"
+	resp, _ := srv.Test(httptest.NewRequest(http.MethodGet, route, nil), 5)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, jwksProvider.LastModified("default").UTC().Format(http.TimeFormat), lastModified)
+	// the max-age is shortened, because a next key is published
+	assert.Equal(t, "public, max-age=60", resp.Header.Get("Cache-Control"))
+
+	tests := []struct {
+		description  string
+		headers      map[string]string
+		expectedCode int
+	}{
+		{
+			description:  "matching ETag",
+			headers:      map[string]string{"If-None-Match": etag},
+			expectedCode: 304,
+		},
+		{
+			description:  "matching ETag in a list of ETags",
+			headers:      map[string]string{"If-None-Match": `"other", W/` + etag},
+			expectedCode: 304,
+		},
+		{
+			description:  "outdated ETag",
+			headers:      map[string]string{"If-None-Match": `"other"`},
+			expectedCode: 200,
+		},
+		{
+			description:  "outdated ETag takes precedence over If-Modified-Since",
+			headers:      map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified},
+			expectedCode: 200,
+		},
+		{
+			description:  "not modified since",
+			headers:      map[string]string{"If-Modified-Since": lastModified},
+			expectedCode: 304,
+		},
+		{
+			description:  "modified since",
+			headers:      map[string]string{"If-Modified-Since": "Mon, 02 Jan 2006 15:04:05 GMT"},
+			expectedCode: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, route, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			resp, _ := srv.Test(req, 5)
+			assert.Equalf(t, tt.expectedCode, resp.StatusCode, tt.description)
+			assert.Equal(t, etag, resp.Header.Get("ETag"))
+
+			bodyBytes, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %v", err)
+			}
+			assert.Equal(t, tt.expectedCode == 304, len(bodyBytes) == 0)
+		})
+	}
+}
+
+func TestDiscoveryRouteCaching(t *testing.T) {
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./router_testdata/",
+		CertFileNameNext:   "missing-tls.crt",
+		KidFileNameNext:    "missing-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+		NextOptional:       true,
+	}
+
+	srv := server.New()
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	handler := server.NewHandler(jwksProvider, server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	})
+	srv.RegisterRoutes(handler)
+
+	newRequest := func(host string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/auth/realms/default/.well-known/openid-configuration", nil)
+		req.Header.Set("X-Forwarded-Host", host)
+		return req
+	}
+
+	resp, _ := srv.Test(newRequest("localhost:8080"), 5)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "public, max-age=300", resp.Header.Get("Cache-Control"))
+	// the issuer URL depends on the forwarded headers, which shared caches have to respect
+	assert.Contains(t, resp.Header.Get("Vary"), "X-Forwarded-Host")
+	assert.Contains(t, resp.Header.Get("Vary"), "Forwarded")
+	etag := resp.Header.Get("ETag")
+
+	// the ETag depends on the content, which differs per host
+	resp, _ = srv.Test(newRequest("example.com"), 5)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+
+	req := newRequest("localhost:8080")
+	req.Header.Set("If-None-Match", etag)
+	resp, _ = srv.Test(req, 5)
+	assert.Equal(t, 304, resp.StatusCode)
+}
+
+func TestJwksRouteCompression(t *testing.T) {
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./router_testdata/",
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+
+	srv := server.New()
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	handler := server.NewHandler(jwksProvider, server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	})
+	srv.RegisterRoutes(handler)
+
+	snapshot := jwksProvider.Snapshot("default")
+
+	tests := []struct {
+		description      string
+		acceptEncoding   string
+		expectedEncoding string
+		expectedBody     []byte
+	}{
+		{
+			description:  "uncompressed without Accept-Encoding",
+			expectedBody: snapshot.JSON,
+		},
+		{
+			description:      "brotli is preferred",
+			acceptEncoding:   "gzip, deflate, br",
+			expectedEncoding: "br",
+			expectedBody:     snapshot.Brotli,
+		},
+		{
+			description:      "gzip",
+			acceptEncoding:   "gzip",
+			expectedEncoding: "gzip",
+			expectedBody:     snapshot.Gzip,
+		},
+		{
+			description:      "brotli is not accepted",
+			acceptEncoding:   "br;q=0, gzip",
+			expectedEncoding: "gzip",
+			expectedBody:     snapshot.Gzip,
+		},
+		{
+			description:      "gzip is sent if the client prefers it",
+			acceptEncoding:   "gzip;q=1, br;q=0.1",
+			expectedEncoding: "gzip",
+			expectedBody:     snapshot.Gzip,
+		},
+		{
+			description:      "brotli breaks a tie",
+			acceptEncoding:   "gzip;q=0.5, br;q=0.5",
+			expectedEncoding: "br",
+			expectedBody:     snapshot.Brotli,
+		},
+		{
+			description:      "wildcard",
+			acceptEncoding:   "gzip;q=0.2, *",
+			expectedEncoding: "br",
+			expectedBody:     snapshot.Brotli,
+		},
+		{
+			description:    "identity is preferred",
+			acceptEncoding: "identity, gzip;q=0.5",
+			expectedBody:   snapshot.JSON,
+		},
+		{
+			description:    "unsupported encoding",
+			acceptEncoding: "deflate",
+			expectedBody:   snapshot.JSON,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/auth/realms/default/protocol/openid-connect/certs", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			resp, _ := srv.Test(req, 5)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, tt.expectedEncoding, resp.Header.Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+
+			bodyBytes, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %v", err)
+			}
+			assert.Equal(t, tt.expectedBody, bodyBytes)
+
+			// every encoding has its own ETag
+			req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+			resp, _ = srv.Test(req, 5)
+			assert.Equal(t, 304, resp.StatusCode)
+		})
+	}
+
+	for _, acceptEncoding := range []string{"identity;q=0, *;q=0", "deflate, identity;q=0", "deflate, *;q=0"} {
+		t.Run("not acceptable: "+acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/auth/realms/default/protocol/openid-connect/certs", nil)
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+			resp, _ := srv.Test(req, 5)
+			assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+			assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		})
+	}
+}
+
+func TestUnknownRealmRoutes(t *testing.T) {
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./router_testdata/",
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+	}
+
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	realmRegistry, err := realm.NewRegistry(&config.RealmConfig{Realms: []string{"default"}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create realm registry: %v", err)
+	}
+
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(jwksProvider, server.HandlerOptions{
+		RealmRegistry:  realmRegistry,
+		IssuerResolver: newTestIssuerResolver(t),
+	}))
+
+	tests := []struct {
+		description  string
+		route        string
+		expectedCode int
+	}{
+		{
+			description:  "discovery of a configured realm",
+			route:        "/auth/realms/default/.well-known/openid-configuration",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "discovery of an unknown realm",
+			route:        "/auth/realms/typo/.well-known/openid-configuration",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "certs of a configured realm",
+			route:        "/auth/realms/default/protocol/openid-connect/certs",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "certs of an unknown realm",
+			route:        "/auth/realms/typo/protocol/openid-connect/certs",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "issuer of a configured realm",
+			route:        "/auth/realms/default",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "issuer of an unknown realm",
+			route:        "/auth/realms/typo",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.route, nil)
+			req.Header.Set("X-Forwarded-Host", "example.com")
+			resp, err := srv.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			assert.Equalf(t, tt.expectedCode, resp.StatusCode, tt.description)
+
+			if tt.expectedCode == http.StatusNotFound {
+				body, _ := io.ReadAll(resp.Body)
+				assert.JSONEq(t, `{"error":"Realm does not exist"}`, string(body))
+			}
+		})
+	}
+}
+
+func TestRealmRoutes(t *testing.T) {
+	jwksConfig := &config.JwksFileConfig{
+		UpdateInterval:     0,
+		MountedPath:        "./router_testdata/",
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+		NextOptional:       true,
+		PrevOptional:       true,
+		RealmMountPaths:    map[string]string{"realm-b": "./router_testdata/"},
+	}
+
+	defaultProvider, err := jwks.NewFileProvider(t.Context(), jwksConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+
+	realmConfig := jwksConfig.ForRealm("realm-b")
+	realmConfig.CertFileNameActive = "ec-tls.crt"
+	realmConfig.KidFileNameActive = "ec-tls.kid"
+	realmConfig.CertFileNameNext = "missing.crt"
+	realmConfig.CertFileNamePrev = "missing.crt"
+	realmProvider, err := jwks.NewFileProvider(t.Context(), realmConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider of realm: %v", err)
+	}
+
+	srv := server.New()
+	jwksProvider := jwks.NewRealmProvider(defaultProvider, map[string]jwks.Provider{"realm-b": realmProvider})
+	// the realm with keys of its own is served, even though it is not configured
+	realmRegistry, err := realm.NewRegistry(&config.RealmConfig{}, jwks.ProviderRealms(jwksProvider))
+	if err != nil {
+		t.Fatalf("failed to create realm registry: %v", err)
+	}
+	srv.RegisterRoutes(server.NewHandler(jwksProvider, server.HandlerOptions{
+		RealmRegistry:  realmRegistry,
+		IssuerResolver: newTestIssuerResolver(t),
+	}))
+
+	getJwks := func(realmName string) server.JwksResponse {
+		req := httptest.NewRequest(http.MethodGet, "/auth/realms/"+realmName+"/protocol/openid-connect/certs", nil)
+		resp, err := srv.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var response server.JwksResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response body: %v", err)
+		}
+		return response
+	}
+
+	defaultKeys := getJwks("default").Keys
+	assert.Len(t, defaultKeys, 3)
+
+	realmKeys := getJwks("realm-b").Keys
+	if assert.Len(t, realmKeys, 1) {
+		assert.Equal(t, "ES256", realmKeys[0].Alg)
+		assert.NotContains(t, []string{defaultKeys[0].Kid, defaultKeys[1].Kid, defaultKeys[2].Kid}, realmKeys[0].Kid)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/realms/realm-b", nil)
+	resp, err := srv.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var defaultRealm jwks.DefaultRealm
+	if err := json.NewDecoder(resp.Body).Decode(&defaultRealm); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	assert.Equal(t, realmProvider.GetDefaultRealm("realm-b").PublicKey, defaultRealm.PublicKey)
+	assert.NotEqual(t, defaultProvider.GetDefaultRealm("default").PublicKey, defaultRealm.PublicKey)
+}
@@ -5,12 +5,25 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/realm"
+	"net"
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
 type FiberServer struct {
 	*fiber.App
+
+	// realmRegistry contains the realms that are used as metrics labels, it is set when the routes are registered
+	realmRegistry *realm.Registry
+	// requireClientCert is set if every request except the health probes needs a verified client certificate
+	requireClientCert bool
 }
 
 func New() *FiberServer {
@@ -22,6 +35,48 @@ func New() *FiberServer {
 	}
 
 	server.App.Use(recover.New())
+	server.App.Use(server.metricsMiddleware)
+	server.App.Use(server.clientCertificateMiddleware)
 
 	return server
 }
+
+// Start listens on the configured port, using HTTPS if a serving certificate is configured.
+func (s *FiberServer) Start(serverConfig *config.ServerConfig) error {
+	addr := fmt.Sprintf(":%d", serverConfig.Port)
+	if !serverConfig.TLS.IsEnabled() {
+		return s.Listen(addr)
+	}
+
+	listener, err := net.Listen(s.Config().Network, addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return s.ServeTLS(listener, &serverConfig.TLS)
+}
+
+// ServeTLS serves HTTPS on the listener until the server is shut down. If client certificates are required, the
+// TLS handshake only verifies a given client certificate and requests without one are rejected afterward, so the
+// probes of the kubelet, which send no client certificate, still reach the health endpoints.
+func (s *FiberServer) ServeTLS(listener net.Listener, tlsConfig *config.TLSConfig) error {
+	// the serving certificate is no longer reloaded once the server is shut down
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverTLSConfig, err := NewTLSConfig(ctx, tlsConfig)
+	if err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+
+	if serverTLSConfig.ClientAuth == tls.RequireAndVerifyClientCert {
+		serverTLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		s.requireClientCert = true
+	}
+	return s.Listener(tls.NewListener(listener, serverTLSConfig))
+}
+
+// StartAdmin listens on the configured address of the admin API.
+func (s *FiberServer) StartAdmin(adminConfig *config.AdminConfig) error {
+	return s.Listen(net.JoinHostPort(adminConfig.Host, strconv.Itoa(adminConfig.Port)))
+}
@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/server"
+	"issuer-service-go/internal/testutil"
+	"issuer-service-go/internal/validation"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	metadataSigningKid = "3F6A9C2E-1B4D-4E7A-8C5F-0D2B6E9A1C47"
+)
+
+func newTestMetadataSigner(t *testing.T) *jwks.MetadataSigner {
+	t.Helper()
+
+	certificate := testutil.NewCertificate(t, testutil.CertificateOptions{})
+	dir := t.TempDir()
+	writeTestFile(t, path.Join(dir, "tls.crt"), certificate.CertPEM)
+	writeTestFile(t, path.Join(dir, "tls.key"), certificate.KeyPEM)
+	writeTestFile(t, path.Join(dir, "tls.kid"), []byte(metadataSigningKid))
+
+	metadataSigner, err := jwks.NewMetadataSigner(t.Context(), &config.MetadataSigningConfig{
+		MountedPath:   dir,
+		CertFileName:  "tls.crt",
+		KeyFileName:   "tls.key",
+		KidFileName:   "tls.kid",
+		TokenLifetime: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to create metadata signer: %v", err)
+	}
+	return metadataSigner
+}
+
+// decodeSignedToken verifies the token with the certificate of its 'x5c' header and returns its claims.
+func decodeSignedToken(t *testing.T, token string, issuer string) map[string]any {
+	t.Helper()
+
+	var header struct {
+		Alg string   `json:"alg"`
+		X5c []string `json:"x5c"`
+	}
+	headerByteArray, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if err != nil {
+		t.Fatalf("failed to decode header: %v", err)
+	}
+	if err = json.Unmarshal(headerByteArray, &header); err != nil {
+		t.Fatalf("failed to unmarshal header: %v", err)
+	}
+
+	jwk := &jwks.Jwk{Kid: metadataSigningKid, Alg: header.Alg, X5c: header.X5c}
+	diagnosis := validation.Validate(token, []*jwks.Jwk{jwk}, validation.Expectations{Issuer: issuer, Now: time.Now()})
+	assert.Truef(t, diagnosis.Valid, "signed token is invalid: %+v", diagnosis.Checks)
+	return diagnosis.Claims
+}
+
+func TestSignedMetadata(t *testing.T) {
+	config.GetConfig().PathPrefix = ""
+
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(newDiscoveryTestProvider(t), server.HandlerOptions{
+		MetadataSigner: newTestMetadataSigner(t),
+		IssuerResolver: newTestIssuerResolver(t),
+	}))
+
+	get := func(route string, accept string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, route, nil)
+		req.Header.Set("X-Forwarded-Host", "example.com")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := srv.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp
+	}
+	issuer := "https://example.com/auth/realms/default"
+
+	t.Run("discovery document contains signed metadata", func(t *testing.T) {
+		resp := get("/auth/realms/default/.well-known/openid-configuration", "")
+		var document map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+			t.Fatalf("failed to decode response body: %v", err)
+		}
+
+		signedMetadata, _ := document["signed_metadata"].(string)
+		claims := decodeSignedToken(t, signedMetadata, issuer)
+		assert.Equal(t, issuer, claims["issuer"])
+		assert.Equal(t, document["jwks_uri"], claims["jwks_uri"])
+		assert.NotContains(t, claims, "signed_metadata")
+
+		// the signed metadata is cached, so the document stays cacheable, but only revalidated by its ETag
+		assert.Empty(t, resp.Header.Get("Last-Modified"))
+		etag := resp.Header.Get("ETag")
+		assert.Equal(t, etag, get("/auth/realms/default/.well-known/openid-configuration", "").Header.Get("ETag"))
+	})
+
+	t.Run("JWKS is sent as JSON by default", func(t *testing.T) {
+		resp := get("/auth/realms/default/protocol/openid-connect/certs", "application/json, */*")
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "Accept, Accept-Encoding", resp.Header.Get("Vary"))
+	})
+
+	t.Run("JWKS is sent as signed JWT if accepted", func(t *testing.T) {
+		resp := get("/auth/realms/default/protocol/openid-connect/certs", "application/jwk-set+jwt")
+		assert.Equal(t, "application/jwk-set+jwt", resp.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		claims := decodeSignedToken(t, string(body), issuer)
+		assert.Equal(t, issuer, claims["sub"])
+		keys, _ := claims["keys"].([]any)
+		assert.Len(t, keys, 3)
+
+		// the JWT is signed again independently of the keys, so If-Modified-Since must not keep an old one
+		assert.Empty(t, resp.Header.Get("Last-Modified"))
+		req := httptest.NewRequest(http.MethodGet, "/auth/realms/default/protocol/openid-connect/certs", nil)
+		req.Header.Set("X-Forwarded-Host", "example.com")
+		req.Header.Set("Accept", "application/jwk-set+jwt")
+		req.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		conditionalResp, err := srv.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer conditionalResp.Body.Close()
+		assert.Equal(t, http.StatusOK, conditionalResp.StatusCode)
+	})
+}
+
+func TestUnsignedMetadata(t *testing.T) {
+	config.GetConfig().PathPrefix = ""
+
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(newDiscoveryTestProvider(t), server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/realms/default/protocol/openid-connect/certs", nil)
+	req.Header.Set("X-Forwarded-Host", "example.com")
+	req.Header.Set("Accept", "application/jwk-set+jwt")
+	resp, err := srv.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"), "the JWKS is only signed if configured")
+}
@@ -0,0 +1,163 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"issuer-service-go/internal/config"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	certificateReloaderName = "TLS Certificate Reloader"
+)
+
+// NewTLSConfig creates the TLS configuration of the server. The serving certificate is reloaded from disk every
+// ReloadInterval until ctx is done, so renewed certificates are used for new connections without a restart.
+func NewTLSConfig(ctx context.Context, tlsConfig *config.TLSConfig) (*tls.Config, error) {
+	if tlsConfig.KeyFile == "" {
+		return nil, errors.New("SERVER_TLS_KEY_FILE must be set if SERVER_TLS_CERT_FILE is set")
+	}
+
+	minVersion, err := parseTLSVersion(tlsConfig.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := parseCipherSuites(tlsConfig.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	reloader := &certificateReloader{certFile: tlsConfig.CertFile, keyFile: tlsConfig.KeyFile}
+	if err = reloader.reload(); err != nil {
+		return nil, err
+	}
+
+	serverTLSConfig := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: reloader.getCertificate,
+	}
+
+	if tlsConfig.ClientCAFile != "" {
+		if err = configureClientAuth(serverTLSConfig, tlsConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	reloader.start(ctx, tlsConfig.ReloadInterval)
+	return serverTLSConfig, nil
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported minimum TLS version '%s'", version)
+}
+
+// parseCipherSuites returns the IDs of the named cipher suites. Only cipher suites without known security
+// issues are supported.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	supported := make(map[string]uint16)
+	for _, cipherSuite := range tls.CipherSuites() {
+		supported[cipherSuite.Name] = cipherSuite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, exists := supported[name]
+		if !exists {
+			return nil, fmt.Errorf("unsupported TLS cipher suite '%s'", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func configureClientAuth(serverTLSConfig *tls.Config, tlsConfig *config.TLSConfig) error {
+	caByteArray, err := os.ReadFile(tlsConfig.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caByteArray) {
+		return fmt.Errorf("client CA bundle %s does not contain any certificate", tlsConfig.ClientCAFile)
+	}
+	serverTLSConfig.ClientCAs = clientCAs
+
+	switch tlsConfig.ClientAuth {
+	case config.ClientAuthRequire:
+		serverTLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	case config.ClientAuthVerifyIfGiven:
+		serverTLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return fmt.Errorf("unsupported client authentication '%s'", tlsConfig.ClientAuth)
+	}
+	return nil
+}
+
+// certificateReloader serves the last successfully loaded certificate.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	certificate atomic.Pointer[tls.Certificate]
+}
+
+func (r *certificateReloader) reload() error {
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load serving certificate: %w", err)
+	}
+	r.certificate.Store(&certificate)
+	return nil
+}
+
+// start reloads the certificate every interval until ctx is done.
+func (r *certificateReloader) start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		log.Info().Msgf("%s is deactivated", certificateReloaderName)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info().Msgf("%s stopped", certificateReloaderName)
+				return
+			case <-ticker.C:
+				if err := r.reload(); err != nil {
+					log.Error().Msgf("failed to reload serving certificate, keeping the current one: %v", err)
+				}
+			}
+		}
+	}()
+	log.Info().Msgf("%s started", certificateReloaderName)
+}
+
+func (r *certificateReloader) getCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.certificate.Load(), nil
+}
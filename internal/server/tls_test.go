@@ -0,0 +1,332 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/server"
+	"issuer-service-go/internal/testutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCA generates a self-signed CA certificate.
+func newTestCA(t *testing.T, serial int64) *testutil.Certificate {
+	t.Helper()
+
+	return testutil.NewCertificate(t, testutil.CertificateOptions{Serial: serial, IsCA: true})
+}
+
+// newTestCertificate generates a certificate for 127.0.0.1 signed by the issuer.
+func newTestCertificate(
+	t *testing.T,
+	serial int64,
+	issuer *testutil.Certificate,
+	extKeyUsage x509.ExtKeyUsage,
+) *testutil.Certificate {
+	t.Helper()
+
+	return testutil.NewCertificate(t, testutil.CertificateOptions{
+		Serial:      serial,
+		Issuer:      issuer,
+		ExtKeyUsage: []x509.ExtKeyUsage{extKeyUsage},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	})
+}
+
+func writeTestFile(t *testing.T, file string, content []byte) {
+	t.Helper()
+
+	// write atomically, so a reload never reads a half-written file
+	if err := os.WriteFile(file+".tmp", content, 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Rename(file+".tmp", file); err != nil {
+		t.Fatalf("failed to rename file: %v", err)
+	}
+}
+
+// startTLSServer starts the server on a random port and returns its address.
+func startTLSServer(t *testing.T, tlsConfig *config.TLSConfig) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(nil, server.HandlerOptions{
+		IssuerResolver: newTestIssuerResolver(t),
+	}))
+	go func() {
+		_ = srv.ServeTLS(listener, tlsConfig)
+	}()
+	t.Cleanup(func() {
+		_ = srv.Shutdown()
+	})
+
+	return listener.Addr().String()
+}
+
+func newTLSClient(ca *testutil.Certificate, clientCertificates ...tls.Certificate) *http.Client {
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.Cert)
+
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				RootCAs:      rootCAs,
+				Certificates: clientCertificates,
+			},
+		},
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, 1)
+	serverCert := newTestCertificate(t, 2, ca, x509.ExtKeyUsageServerAuth)
+	writeTestFile(t, path.Join(dir, "tls.crt"), serverCert.CertPEM)
+	writeTestFile(t, path.Join(dir, "tls.key"), serverCert.KeyPEM)
+	writeTestFile(t, path.Join(dir, "ca.crt"), ca.CertPEM)
+	writeTestFile(t, path.Join(dir, "empty.crt"), []byte("no certificate"))
+
+	tests := []struct {
+		description string
+		tlsConfig   config.TLSConfig
+		err         bool
+	}{
+		{
+			description: "valid configuration",
+			tlsConfig: config.TLSConfig{
+				CertFile:     path.Join(dir, "tls.crt"),
+				KeyFile:      path.Join(dir, "tls.key"),
+				MinVersion:   "1.2",
+				CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+				ClientCAFile: path.Join(dir, "ca.crt"),
+				ClientAuth:   config.ClientAuthVerifyIfGiven,
+			},
+		},
+		{
+			description: "missing key file",
+			tlsConfig:   config.TLSConfig{CertFile: path.Join(dir, "tls.crt"), MinVersion: "1.2"},
+			err:         true,
+		},
+		{
+			description: "unsupported minimum version",
+			tlsConfig: config.TLSConfig{
+				CertFile: path.Join(dir, "tls.crt"), KeyFile: path.Join(dir, "tls.key"), MinVersion: "1.0",
+			},
+			err: true,
+		},
+		{
+			description: "insecure cipher suite",
+			tlsConfig: config.TLSConfig{
+				CertFile: path.Join(dir, "tls.crt"), KeyFile: path.Join(dir, "tls.key"), MinVersion: "1.2",
+				CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"},
+			},
+			err: true,
+		},
+		{
+			description: "certificate does not exist",
+			tlsConfig: config.TLSConfig{
+				CertFile: path.Join(dir, "missing.crt"), KeyFile: path.Join(dir, "tls.key"), MinVersion: "1.2",
+			},
+			err: true,
+		},
+		{
+			description: "client CA bundle without certificate",
+			tlsConfig: config.TLSConfig{
+				CertFile: path.Join(dir, "tls.crt"), KeyFile: path.Join(dir, "tls.key"), MinVersion: "1.2",
+				ClientCAFile: path.Join(dir, "empty.crt"), ClientAuth: config.ClientAuthRequire,
+			},
+			err: true,
+		},
+		{
+			description: "unsupported client authentication",
+			tlsConfig: config.TLSConfig{
+				CertFile: path.Join(dir, "tls.crt"), KeyFile: path.Join(dir, "tls.key"), MinVersion: "1.2",
+				ClientCAFile: path.Join(dir, "ca.crt"), ClientAuth: "optional",
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			_, err := server.NewTLSConfig(t.Context(), &tt.tlsConfig)
+			assert.Equalf(t, tt.err, err != nil, "expected error: %v, got: %v", tt.err, err)
+		})
+	}
+}
+
+func TestTLSServer(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, 1)
+	serverCert := newTestCertificate(t, 2, ca, x509.ExtKeyUsageServerAuth)
+	writeTestFile(t, path.Join(dir, "tls.crt"), serverCert.CertPEM)
+	writeTestFile(t, path.Join(dir, "tls.key"), serverCert.KeyPEM)
+
+	addr := startTLSServer(t, &config.TLSConfig{
+		CertFile:       path.Join(dir, "tls.crt"),
+		KeyFile:        path.Join(dir, "tls.key"),
+		MinVersion:     "1.3",
+		ReloadInterval: 50 * time.Millisecond,
+	})
+
+	resp, err := newTLSClient(ca).Get("https://" + addr + "/health")
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, uint16(tls.VersionTLS13), resp.TLS.Version)
+	assert.Equal(t, serverCert.Cert.SerialNumber, resp.TLS.PeerCertificates[0].SerialNumber)
+
+	// clients that do not support the minimum version are rejected
+	client := newTLSClient(ca)
+	client.Transport.(*http.Transport).TLSClientConfig.MaxVersion = tls.VersionTLS12
+	_, err = client.Get("https://" + addr + "/health")
+	assert.Error(t, err)
+
+	// a renewed certificate is served without restart
+	renewedCert := newTestCertificate(t, 3, ca, x509.ExtKeyUsageServerAuth)
+	writeTestFile(t, path.Join(dir, "tls.key"), renewedCert.KeyPEM)
+	writeTestFile(t, path.Join(dir, "tls.crt"), renewedCert.CertPEM)
+
+	assert.Eventually(t, func() bool {
+		resp, err = newTLSClient(ca).Get("https://" + addr + "/health")
+		return err == nil && resp.TLS.PeerCertificates[0].SerialNumber.Cmp(renewedCert.Cert.SerialNumber) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestMutualTLSServer(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, 1)
+	serverCert := newTestCertificate(t, 2, ca, x509.ExtKeyUsageServerAuth)
+	clientCert := newTestCertificate(t, 3, ca, x509.ExtKeyUsageClientAuth)
+	otherCA := newTestCA(t, 4)
+	untrustedClientCert := newTestCertificate(t, 5, otherCA, x509.ExtKeyUsageClientAuth)
+	writeTestFile(t, path.Join(dir, "tls.crt"), serverCert.CertPEM)
+	writeTestFile(t, path.Join(dir, "tls.key"), serverCert.KeyPEM)
+	writeTestFile(t, path.Join(dir, "ca.crt"), ca.CertPEM)
+
+	tests := []struct {
+		description        string
+		clientAuth         string
+		clientCertificates []tls.Certificate
+		err                bool
+		expectedCode       int
+	}{
+		{
+			description:        "trusted client certificate",
+			clientAuth:         config.ClientAuthRequire,
+			clientCertificates: []tls.Certificate{clientCert.TLSCertificate(t)},
+			expectedCode:       http.StatusOK,
+		},
+		{
+			description:  "missing client certificate",
+			clientAuth:   config.ClientAuthRequire,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			description:        "untrusted client certificate",
+			clientAuth:         config.ClientAuthRequire,
+			clientCertificates: []tls.Certificate{untrustedClientCert.TLSCertificate(t)},
+			err:                true,
+		},
+		{
+			description:  "missing client certificate is allowed if it is optional",
+			clientAuth:   config.ClientAuthVerifyIfGiven,
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:        "untrusted client certificate is rejected if it is optional",
+			clientAuth:         config.ClientAuthVerifyIfGiven,
+			clientCertificates: []tls.Certificate{untrustedClientCert.TLSCertificate(t)},
+			err:                true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			addr := startTLSServer(t, &config.TLSConfig{
+				CertFile:     path.Join(dir, "tls.crt"),
+				KeyFile:      path.Join(dir, "tls.key"),
+				MinVersion:   "1.2",
+				ClientCAFile: path.Join(dir, "ca.crt"),
+				ClientAuth:   tt.clientAuth,
+			})
+
+			resp, err := newTLSClient(ca, tt.clientCertificates...).Get("https://" + addr + "/metrics")
+			assert.Equalf(t, tt.err, err != nil, "expected error: %v, got: %v", tt.err, err)
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+
+			// the probes of the kubelet send no client certificate
+			probeResp, err := newTLSClient(ca).Get("https://" + addr + "/health/live")
+			if err != nil {
+				t.Fatalf("failed to send request: %v", err)
+			}
+			defer probeResp.Body.Close()
+			assert.Equal(t, http.StatusOK, probeResp.StatusCode)
+		})
+	}
+}
+
+func TestMutualTLSServerRejectsRequestsWithoutClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, 1)
+	serverCert := newTestCertificate(t, 2, ca, x509.ExtKeyUsageServerAuth)
+	writeTestFile(t, path.Join(dir, "tls.crt"), serverCert.CertPEM)
+	writeTestFile(t, path.Join(dir, "tls.key"), serverCert.KeyPEM)
+	writeTestFile(t, path.Join(dir, "ca.crt"), ca.CertPEM)
+
+	addr := startTLSServer(t, &config.TLSConfig{
+		CertFile:     path.Join(dir, "tls.crt"),
+		KeyFile:      path.Join(dir, "tls.key"),
+		MinVersion:   "1.2",
+		ClientCAFile: path.Join(dir, "ca.crt"),
+		ClientAuth:   config.ClientAuthRequire,
+	})
+
+	tests := []struct {
+		path         string
+		expectedCode int
+	}{
+		{path: "/metrics", expectedCode: http.StatusForbidden},
+		{path: "/auth/realms/default/.well-known/openid-configuration", expectedCode: http.StatusForbidden},
+		{path: "/.well-known/oauth-authorization-server/auth/realms/default", expectedCode: http.StatusForbidden},
+		{path: "/auth/realms/default/protocol/openid-connect/certs", expectedCode: http.StatusForbidden},
+		{path: "/healthz", expectedCode: http.StatusForbidden},
+		{path: "/health/ready/../../metrics", expectedCode: http.StatusForbidden},
+		{path: "/health", expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := newTLSClient(ca).Get("https://" + addr + tt.path)
+			if err != nil {
+				t.Fatalf("failed to send request: %v", err)
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+		})
+	}
+}
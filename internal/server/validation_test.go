@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/realm"
+	"issuer-service-go/internal/server"
+	"issuer-service-go/internal/testutil"
+	"issuer-service-go/internal/validation"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	validationTestKid = "8D1C4B5E-2F3A-4E6B-9C7D-0A1B2C3D4E5F"
+)
+
+// signTestToken creates an ES256 JWT with the given claims, which is signed with the key of the certificate.
+func signTestToken(t *testing.T, certificate *testutil.Certificate, claims map[string]any) string {
+	t.Helper()
+
+	encode := func(v any) string {
+		segmentByteArray, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to marshal segment: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(segmentByteArray)
+	}
+
+	signingInput := encode(map[string]string{"alg": "ES256", "kid": validationTestKid}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, certificate.Key.(*ecdsa.PrivateKey), digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// newValidationTestProvider creates a provider, which serves the certificate as active key with validationTestKid.
+func newValidationTestProvider(t *testing.T, certificate *testutil.Certificate) jwks.Provider {
+	t.Helper()
+
+	return newFileTestProvider(t, t.TempDir(), certificate)
+}
+
+// newFileTestProvider writes the certificate as active key with validationTestKid to dir and creates a file
+// provider of dir.
+func newFileTestProvider(t *testing.T, dir string, certificate *testutil.Certificate) *jwks.FileProvider {
+	t.Helper()
+
+	writeTestFile(t, path.Join(dir, "tls.crt"), certificate.CertPEM)
+	writeTestFile(t, path.Join(dir, "tls.kid"), []byte(validationTestKid))
+
+	jwksProvider, err := jwks.NewFileProvider(t.Context(), &config.JwksFileConfig{
+		MountedPath:        dir,
+		CertFileNameNext:   "next-tls.crt",
+		KidFileNameNext:    "next-tls.kid",
+		CertFileNameActive: "tls.crt",
+		KidFileNameActive:  "tls.kid",
+		CertFileNamePrev:   "prev-tls.crt",
+		KidFileNamePrev:    "prev-tls.kid",
+		NextOptional:       true,
+		PrevOptional:       true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWKS file provider: %v", err)
+	}
+	return jwksProvider
+}
+
+func TestValidationRoute(t *testing.T) {
+	config.GetConfig().PathPrefix = ""
+
+	certificate := testutil.NewCertificate(t, testutil.CertificateOptions{})
+	jwksProvider := newValidationTestProvider(t, certificate)
+
+	realmRegistry, err := realm.NewRegistry(&config.RealmConfig{Realms: []string{"default"}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create realm registry: %v", err)
+	}
+
+	srv := server.New()
+	srv.RegisterRoutes(server.NewHandler(jwksProvider, server.HandlerOptions{
+		RealmRegistry:  realmRegistry,
+		IssuerResolver: newTestIssuerResolver(t),
+	}))
+
+	validToken := signTestToken(t, certificate, map[string]any{
+		"iss": "https://example.com/auth/realms/default",
+		"exp": time.Now().Add(time.Minute).Unix(),
+		"aud": "gateway",
+	})
+	expiredToken := signTestToken(t, certificate, map[string]any{
+		"iss": "https://example.com/auth/realms/default",
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	})
+
+	tests := []struct {
+		description         string
+		route               string
+		host                string
+		body                string
+		expectedCode        int
+		expectedValid       bool
+		expectedFailedCheck string
+	}{
+		{
+			description:   "valid token",
+			route:         "/api/v1/validate/default",
+			host:          "example.com",
+			body:          `{"token": "` + validToken + `", "audience": "gateway"}`,
+			expectedCode:  http.StatusOK,
+			expectedValid: true,
+		},
+		{
+			description:         "audience does not match",
+			route:               "/api/v1/validate/default",
+			host:                "example.com",
+			body:                `{"token": "` + validToken + `", "audience": "other"}`,
+			expectedCode:        http.StatusOK,
+			expectedFailedCheck: validation.CheckAudience,
+		},
+		{
+			description:         "expired token",
+			route:               "/api/v1/validate/default",
+			host:                "example.com",
+			body:                `{"token": "` + expiredToken + `"}`,
+			expectedCode:        http.StatusOK,
+			expectedFailedCheck: validation.CheckExpiry,
+		},
+		{
+			description:         "issuer of another host",
+			route:               "/api/v1/validate/default",
+			host:                "other.example.com",
+			body:                `{"token": "` + validToken + `"}`,
+			expectedCode:        http.StatusOK,
+			expectedFailedCheck: validation.CheckIssuer,
+		},
+		{
+			description:  "missing token",
+			route:        "/api/v1/validate/default",
+			host:         "example.com",
+			body:         `{"audience": "gateway"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "missing X-Forwarded-Host header",
+			route:        "/api/v1/validate/default",
+			body:         `{"token": "` + validToken + `"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "unknown realm",
+			route:        "/api/v1/validate/unknown",
+			host:         "example.com",
+			body:         `{"token": "` + validToken + `"}`,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.route, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.host != "" {
+				req.Header.Set("X-Forwarded-Host", tt.host)
+			}
+
+			resp, err := srv.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var diagnosis validation.Diagnosis
+			if err := json.NewDecoder(resp.Body).Decode(&diagnosis); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			assert.Equal(t, tt.expectedValid, diagnosis.Valid)
+			assert.Equal(t, tt.expectedFailedCheck, diagnosis.FailedCheck)
+			assert.Equal(t, validationTestKid, diagnosis.Kid)
+			assert.Equal(t, validationTestKid, diagnosis.ExpectedKid)
+			assert.Equal(t, "active", diagnosis.KeySlot)
+			assert.Equal(t, "https://"+tt.host+"/auth/realms/default", diagnosis.ExpectedIssuer)
+		})
+	}
+}
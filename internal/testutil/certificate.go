@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package testutil contains the fixtures shared by the tests of several packages.
+package testutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// Certificate is a generated certificate with its private key.
+type Certificate struct {
+	Cert    *x509.Certificate
+	Key     crypto.Signer
+	CertPEM []byte
+	KeyPEM  []byte // PKCS #8 encoded private key
+}
+
+// CertificateOptions configures a generated certificate. The zero value creates a self-signed certificate with
+// a new P-256 key, which is valid from an hour ago until an hour from now.
+type CertificateOptions struct {
+	Serial      int64         // 1 if not set
+	Key         crypto.Signer // a new P-256 key if not set
+	Issuer      *Certificate  // the certificate is self-signed if not set
+	IsCA        bool
+	ExtKeyUsage []x509.ExtKeyUsage
+	IPAddresses []net.IP
+	NotBefore   time.Time
+	NotAfter    time.Time
+}
+
+// NewCertificate generates a certificate with the given options.
+func NewCertificate(t testing.TB, options CertificateOptions) *Certificate {
+	t.Helper()
+
+	key := options.Key
+	if key == nil {
+		var err error
+		if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(max(options.Serial, 1)),
+		Subject:      pkix.Name{CommonName: "issuer-service-go"},
+		NotBefore:    options.NotBefore,
+		NotAfter:     options.NotAfter,
+		IPAddresses:  options.IPAddresses,
+		ExtKeyUsage:  options.ExtKeyUsage,
+	}
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(time.Hour)
+	}
+	if options.IsCA {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	}
+
+	parent, signer := template, key
+	if options.Issuer != nil {
+		parent, signer = options.Issuer.Cert, options.Issuer.Key
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+
+	return &Certificate{
+		Cert:    cert,
+		Key:     key,
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		KeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// TLSCertificate returns the certificate with its private key for a TLS client or server.
+func (c *Certificate) TLSCertificate(t testing.TB) tls.Certificate {
+	t.Helper()
+
+	certificate, err := tls.X509KeyPair(c.CertPEM, c.KeyPEM)
+	if err != nil {
+		t.Fatalf("failed to load key pair: %v", err)
+	}
+	return certificate
+}
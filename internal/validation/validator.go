@@ -0,0 +1,348 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package validation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"issuer-service-go/internal/config"
+	"issuer-service-go/internal/jwks"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	CheckFormat    = "format"
+	CheckKid       = "kid"
+	CheckAlg       = "alg"
+	CheckSignature = "signature"
+	CheckIssuer    = "iss"
+	CheckExpiry    = "exp"
+	CheckNotBefore = "nbf"
+	CheckAudience  = "aud"
+)
+
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+const (
+	jwtParts = 3
+)
+
+// Expectations contains the values the claims of a token are checked against.
+type Expectations struct {
+	Issuer   string
+	Audience string // if empty, the audience is not checked
+	Now      time.Time
+}
+
+// Diagnosis describes the result of the validation of a token.
+type Diagnosis struct {
+	Valid       bool    `json:"valid"`
+	FailedCheck string  `json:"failed_check,omitempty"` // first check that failed
+	Checks      []Check `json:"checks"`
+
+	Kid         string `json:"kid,omitempty"`          // key ID of the token header
+	Alg         string `json:"alg,omitempty"`          // algorithm of the token header
+	KeySlot     string `json:"key_slot,omitempty"`     // slot of the key the token was signed with, e.g. 'active'
+	ExpectedKid string `json:"expected_kid,omitempty"` // key ID of the active key, which new tokens are signed with
+
+	ExpectedIssuer string `json:"expected_issuer"`
+	Keys           []Key  `json:"keys"` // keys the token can be verified with
+
+	Claims map[string]any `json:"-"` // claims of the token, nil if the token could not be parsed
+}
+
+// Check is the result of a single check of the validation.
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Key describes a published key.
+type Key struct {
+	Kid  string `json:"kid"`
+	Alg  string `json:"alg"`
+	Slot string `json:"slot,omitempty"`
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type claims struct {
+	Issuer    *string         `json:"iss"`
+	Expiry    *json.Number    `json:"exp"`
+	NotBefore *json.Number    `json:"nbf"`
+	Audience  json.RawMessage `json:"aud"`
+}
+
+// Validate verifies the signature of the token with the matching published key and checks its claims. The
+// claims are checked even if the signature is invalid, so every problem of the token is reported at once.
+func Validate(token string, keys []*jwks.Jwk, expectations Expectations) *Diagnosis {
+	diagnosis := &Diagnosis{
+		Valid:          true,
+		Checks:         make([]Check, 0, 8), //nolint:mnd // number of checks
+		ExpectedIssuer: expectations.Issuer,
+		Keys:           make([]Key, 0, len(keys)),
+	}
+	for _, jwk := range keys {
+		diagnosis.Keys = append(diagnosis.Keys, Key{Kid: jwk.Kid, Alg: jwk.Alg, Slot: jwk.Slot})
+		if jwk.Slot == config.Active.String() {
+			diagnosis.ExpectedKid = jwk.Kid
+		}
+	}
+
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	tokenHeader, tokenClaims, signature, err := parse(parts, &diagnosis.Claims)
+	if err != nil {
+		diagnosis.Claims = nil
+		diagnosis.fail(CheckFormat, err.Error())
+		diagnosis.skip(CheckKid, CheckAlg, CheckSignature, CheckIssuer, CheckExpiry, CheckNotBefore, CheckAudience)
+		return diagnosis
+	}
+	diagnosis.pass(CheckFormat, "")
+	diagnosis.Kid, diagnosis.Alg = tokenHeader.Kid, tokenHeader.Alg
+
+	diagnosis.checkKey(tokenHeader, keys, []byte(parts[0]+"."+parts[1]), signature)
+	diagnosis.checkIssuer(tokenClaims, expectations.Issuer)
+	diagnosis.checkTime(CheckExpiry, tokenClaims.Expiry, expectations.Now)
+	diagnosis.checkTime(CheckNotBefore, tokenClaims.NotBefore, expectations.Now)
+	diagnosis.checkAudience(tokenClaims, expectations.Audience)
+	return diagnosis
+}
+
+func parse(parts []string, claimsMap *map[string]any) (*header, *claims, []byte, error) {
+	if len(parts) != jwtParts {
+		return nil, nil, nil, fmt.Errorf("token consists of %d instead of %d parts", len(parts), jwtParts)
+	}
+
+	var tokenHeader header
+	if err := decodeSegment(parts[0], &tokenHeader); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid header: %w", err)
+	}
+
+	var tokenClaims claims
+	if err := decodeSegment(parts[1], &tokenClaims); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	if err := decodeSegment(parts[1], claimsMap); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	return &tokenHeader, &tokenClaims, signature, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	segmentByteArray, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(segmentByteArray))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// checkKey checks that the token was signed by a published key with the algorithm of the key.
+func (d *Diagnosis) checkKey(tokenHeader *header, keys []*jwks.Jwk, signingInput []byte, signature []byte) {
+	index := slices.IndexFunc(keys, func(jwk *jwks.Jwk) bool { return jwk.Kid == tokenHeader.Kid })
+	if index < 0 {
+		d.fail(CheckKid, fmt.Sprintf("no published key with kid '%s'", tokenHeader.Kid))
+		d.skip(CheckAlg, CheckSignature)
+		return
+	}
+	jwk := keys[index]
+	d.KeySlot = jwk.Slot
+	d.pass(CheckKid, fmt.Sprintf("key is published in slot '%s'", jwk.Slot))
+
+	if tokenHeader.Alg != jwk.Alg {
+		d.fail(CheckAlg, fmt.Sprintf("token is signed with '%s', but the key uses '%s'", tokenHeader.Alg, jwk.Alg))
+		d.skip(CheckSignature)
+		return
+	}
+	d.pass(CheckAlg, "")
+
+	if err := verify(jwk, signingInput, signature); err != nil {
+		d.fail(CheckSignature, err.Error())
+		return
+	}
+	d.pass(CheckSignature, "")
+}
+
+func (d *Diagnosis) checkIssuer(tokenClaims *claims, expectedIssuer string) {
+	switch {
+	case tokenClaims.Issuer == nil:
+		d.fail(CheckIssuer, "claim is missing")
+	case *tokenClaims.Issuer != expectedIssuer:
+		d.fail(CheckIssuer, fmt.Sprintf("'%s' does not match '%s'", *tokenClaims.Issuer, expectedIssuer))
+	default:
+		d.pass(CheckIssuer, "")
+	}
+}
+
+// checkTime checks that the token is expired (exp) or valid (nbf) at the given time. A missing 'exp' claim
+// fails the check, a missing 'nbf' claim is skipped.
+func (d *Diagnosis) checkTime(name string, claim *json.Number, now time.Time) {
+	if claim == nil {
+		if name == CheckExpiry {
+			d.fail(name, "claim is missing")
+			return
+		}
+		d.skip(name)
+		return
+	}
+
+	seconds, err := claim.Float64()
+	if err != nil {
+		d.fail(name, fmt.Sprintf("'%s' is no numeric date", claim.String()))
+		return
+	}
+	claimTime := time.Unix(int64(seconds), 0).UTC()
+
+	switch {
+	case name == CheckExpiry && !now.Before(claimTime):
+		d.fail(name, fmt.Sprintf("token expired at %s", claimTime.Format(time.RFC3339)))
+	case name == CheckNotBefore && now.Before(claimTime):
+		d.fail(name, fmt.Sprintf("token is not valid before %s", claimTime.Format(time.RFC3339)))
+	default:
+		d.pass(name, claimTime.Format(time.RFC3339))
+	}
+}
+
+func (d *Diagnosis) checkAudience(tokenClaims *claims, expectedAudience string) {
+	if expectedAudience == "" {
+		d.skip(CheckAudience)
+		return
+	}
+
+	var audiences []string
+	if len(tokenClaims.Audience) > 0 {
+		var audience string
+		if err := json.Unmarshal(tokenClaims.Audience, &audience); err == nil {
+			audiences = []string{audience}
+		} else if err = json.Unmarshal(tokenClaims.Audience, &audiences); err != nil {
+			d.fail(CheckAudience, "claim is neither a string nor an array of strings")
+			return
+		}
+	}
+
+	if !slices.Contains(audiences, expectedAudience) {
+		d.fail(CheckAudience, fmt.Sprintf("%v does not contain '%s'", audiences, expectedAudience))
+		return
+	}
+	d.pass(CheckAudience, "")
+}
+
+func (d *Diagnosis) pass(name string, message string) {
+	d.Checks = append(d.Checks, Check{Name: name, Status: StatusPassed, Message: message})
+}
+
+func (d *Diagnosis) fail(name string, message string) {
+	d.Checks = append(d.Checks, Check{Name: name, Status: StatusFailed, Message: message})
+	if d.Valid {
+		d.Valid = false
+		d.FailedCheck = name
+	}
+}
+
+func (d *Diagnosis) skip(names ...string) {
+	for _, name := range names {
+		d.Checks = append(d.Checks, Check{Name: name, Status: StatusSkipped})
+	}
+}
+
+// verify verifies the JWS signature of the signing input with the public key of the JWK.
+func verify(jwk *jwks.Jwk, signingInput []byte, signature []byte) error {
+	publicKey, err := publicKeyOf(jwk)
+	if err != nil {
+		return err
+	}
+
+	if jwk.Alg == "EdDSA" {
+		edKey, ok := publicKey.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(edKey, signingInput, signature) {
+			return errors.New("signature is invalid")
+		}
+		return nil
+	}
+
+	hash, err := jwks.Hash(jwk.Alg)
+	if err != nil {
+		return err
+	}
+	hasher := hash.New()
+	hasher.Write(signingInput)
+	digest := hasher.Sum(nil)
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(jwk.Alg, "PS") {
+			err = rsa.VerifyPSS(key, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			err = rsa.VerifyPKCS1v15(key, hash, digest, signature)
+		}
+	case *ecdsa.PublicKey:
+		err = verifyECDSA(key, digest, signature)
+	default:
+		err = fmt.Errorf("unsupported key type %T", publicKey)
+	}
+	if err != nil {
+		return fmt.Errorf("signature is invalid: %w", err)
+	}
+	return nil
+}
+
+// verifyECDSA verifies a JWS ECDSA signature, which is the concatenation of R and S with the size of the curve.
+func verifyECDSA(key *ecdsa.PublicKey, digest []byte, signature []byte) error {
+	size := (key.Curve.Params().BitSize + 7) / 8 //nolint:mnd // bits to bytes
+	if len(signature) != 2*size {
+		return fmt.Errorf("signature has %d instead of %d bytes", len(signature), 2*size)
+	}
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(key, digest, r, s) {
+		return errors.New("verification failed")
+	}
+	return nil
+}
+
+func publicKeyOf(jwk *jwks.Jwk) (crypto.PublicKey, error) {
+	if len(jwk.X5c) == 0 {
+		return nil, fmt.Errorf("key with kid '%s' has no certificate", jwk.Kid)
+	}
+
+	certDER, err := base64.StdEncoding.DecodeString(jwk.X5c[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode certificate of key with kid '%s': %w", jwk.Kid, err)
+	}
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate of key with kid '%s': %w", jwk.Kid, err)
+	}
+	return cert.PublicKey, nil
+}
@@ -0,0 +1,266 @@
+// SPDX-FileCopyrightText: 2025 Deutsche Telekom AG
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package validation_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"issuer-service-go/internal/jwks"
+	"issuer-service-go/internal/testutil"
+	"issuer-service-go/internal/validation"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	issuer = "https://gateway.example.com/auth/realms/default"
+)
+
+type testKey struct {
+	jwk    *jwks.Jwk
+	signer crypto.Signer
+}
+
+func newTestKey(t *testing.T, kid string, alg string, slot string, signer crypto.Signer) testKey {
+	t.Helper()
+
+	certificate := testutil.NewCertificate(t, testutil.CertificateOptions{Key: signer})
+
+	return testKey{
+		jwk:    &jwks.Jwk{Kid: kid, Alg: alg, Slot: slot, X5c: jwks.X5c(certificate.Cert)},
+		signer: signer,
+	}
+}
+
+// sign creates a JWT with the given header and claims, which is signed with the key.
+func (k testKey) sign(t *testing.T, header map[string]any, claims map[string]any) string {
+	t.Helper()
+
+	signingInput := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	var err error
+	switch signer := k.signer.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, signer, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, signer, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(signer, []byte(signingInput))
+	}
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+
+	segmentByteArray, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(segmentByteArray)
+}
+
+func newTestKeys(t *testing.T) (testKey, testKey, testKey) {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+
+	return newTestKey(t, "next", "ES256", "next", ecKey),
+		newTestKey(t, "active", "RS256", "active", rsaKey),
+		newTestKey(t, "previous", "EdDSA", "previous", edKey)
+}
+
+func TestValidate(t *testing.T) {
+	nextKey, activeKey, previousKey := newTestKeys(t)
+	keys := []*jwks.Jwk{nextKey.jwk, activeKey.jwk, previousKey.jwk}
+
+	now := time.Now()
+	validClaims := func() map[string]any {
+		return map[string]any{
+			"iss": issuer,
+			"exp": now.Add(time.Minute).Unix(),
+			"nbf": now.Add(-time.Minute).Unix(),
+			"aud": []string{"gateway", "other"},
+		}
+	}
+	withClaim := func(name string, value any) map[string]any {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name            string
+		token           string
+		audience        string
+		expectedFailure string
+		expectedSlot    string
+	}{
+		{
+			name:         "token signed with the active key is valid",
+			token:        activeKey.sign(t, map[string]any{"alg": "RS256", "kid": "active"}, validClaims()),
+			audience:     "gateway",
+			expectedSlot: "active",
+		},
+		{
+			name:         "token signed with the next EC key is valid",
+			token:        nextKey.sign(t, map[string]any{"alg": "ES256", "kid": "next"}, validClaims()),
+			expectedSlot: "next",
+		},
+		{
+			name:         "token signed with the previous Ed25519 key is valid",
+			token:        previousKey.sign(t, map[string]any{"alg": "EdDSA", "kid": "previous"}, validClaims()),
+			audience:     "other",
+			expectedSlot: "previous",
+		},
+		{
+			name:            "token is no JWT",
+			token:           "no.jwt",
+			expectedFailure: validation.CheckFormat,
+		},
+		{
+			name:            "kid is not published",
+			token:           activeKey.sign(t, map[string]any{"alg": "RS256", "kid": "unknown"}, validClaims()),
+			expectedFailure: validation.CheckKid,
+		},
+		{
+			name:            "alg does not match the key",
+			token:           activeKey.sign(t, map[string]any{"alg": "none", "kid": "active"}, validClaims()),
+			expectedFailure: validation.CheckAlg,
+			expectedSlot:    "active",
+		},
+		{
+			name:            "token is signed with another key",
+			token:           nextKey.sign(t, map[string]any{"alg": "ES256", "kid": "active"}, validClaims()),
+			expectedFailure: validation.CheckAlg,
+			expectedSlot:    "active",
+		},
+		{
+			name:            "signature does not match the kid",
+			token:           activeKey.sign(t, map[string]any{"alg": "EdDSA", "kid": "previous"}, validClaims()),
+			expectedFailure: validation.CheckSignature,
+			expectedSlot:    "previous",
+		},
+		{
+			name: "issuer does not match",
+			token: activeKey.sign(t, map[string]any{"alg": "RS256", "kid": "active"},
+				withClaim("iss", "https://other.example.com/auth/realms/default")),
+			expectedFailure: validation.CheckIssuer,
+			expectedSlot:    "active",
+		},
+		{
+			name: "token is expired",
+			token: activeKey.sign(t, map[string]any{"alg": "RS256", "kid": "active"},
+				withClaim("exp", now.Add(-time.Second).Unix())),
+			expectedFailure: validation.CheckExpiry,
+			expectedSlot:    "active",
+		},
+		{
+			name:            "token without expiry",
+			token:           activeKey.sign(t, map[string]any{"alg": "RS256", "kid": "active"}, withClaim("exp", nil)),
+			expectedFailure: validation.CheckExpiry,
+			expectedSlot:    "active",
+		},
+		{
+			name: "token is not yet valid",
+			token: activeKey.sign(t, map[string]any{"alg": "RS256", "kid": "active"},
+				withClaim("nbf", now.Add(time.Minute).Unix())),
+			expectedFailure: validation.CheckNotBefore,
+			expectedSlot:    "active",
+		},
+		{
+			name:            "audience does not match",
+			token:           activeKey.sign(t, map[string]any{"alg": "RS256", "kid": "active"}, withClaim("aud", "other")),
+			audience:        "gateway",
+			expectedFailure: validation.CheckAudience,
+			expectedSlot:    "active",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnosis := validation.Validate(tt.token, keys, validation.Expectations{
+				Issuer:   issuer,
+				Audience: tt.audience,
+				Now:      now,
+			})
+
+			assert.Equal(t, tt.expectedFailure == "", diagnosis.Valid)
+			assert.Equal(t, tt.expectedFailure, diagnosis.FailedCheck)
+			assert.Equal(t, tt.expectedSlot, diagnosis.KeySlot)
+			assert.Equal(t, "active", diagnosis.ExpectedKid)
+			assert.Equal(t, issuer, diagnosis.ExpectedIssuer)
+			assert.Len(t, diagnosis.Keys, len(keys))
+			assert.Len(t, diagnosis.Checks, 8)
+		})
+	}
+}
+
+// TestValidateReportsAllFailures ensures that the claims are checked even if the signature is invalid.
+func TestValidateReportsAllFailures(t *testing.T) {
+	_, activeKey, _ := newTestKeys(t)
+
+	token := activeKey.sign(t, map[string]any{"alg": "RS256", "kid": "active"}, map[string]any{
+		"iss": "https://other.example.com",
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	})
+	token = token[:len(token)-4] + "AAAA"
+
+	diagnosis := validation.Validate(token, []*jwks.Jwk{activeKey.jwk}, validation.Expectations{
+		Issuer: issuer,
+		Now:    time.Now(),
+	})
+
+	statuses := make(map[string]string, len(diagnosis.Checks))
+	for _, check := range diagnosis.Checks {
+		statuses[check.Name] = check.Status
+	}
+	assert.Equal(t, map[string]string{
+		validation.CheckFormat:    validation.StatusPassed,
+		validation.CheckKid:       validation.StatusPassed,
+		validation.CheckAlg:       validation.StatusPassed,
+		validation.CheckSignature: validation.StatusFailed,
+		validation.CheckIssuer:    validation.StatusFailed,
+		validation.CheckExpiry:    validation.StatusFailed,
+		validation.CheckNotBefore: validation.StatusSkipped,
+		validation.CheckAudience:  validation.StatusSkipped,
+	}, statuses)
+	assert.Equal(t, validation.CheckSignature, diagnosis.FailedCheck)
+}